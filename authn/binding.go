@@ -0,0 +1,322 @@
+package authn
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"xiaoshiai.cn/common/log"
+	"xiaoshiai.cn/common/rest/api"
+)
+
+// Token binding binds a session or an API token to a key held by the client,
+// a stolen token is useless without the key.
+//
+// Authenticators set the binding of the token into [api.UserInfo.Extra] by [SetTokenBinding],
+// e.g. from the "cnf" claim of a JWT or from the session record written at sign in,
+// and [NewTokenBindingFilter] validates it on each request.
+const (
+	// ExtraCertificateThumbprint is the base64url SHA-256 thumbprint of the client certificate(RFC 8705 "x5t#S256").
+	ExtraCertificateThumbprint = "cnf.x5t#S256"
+	// ExtraDPoPKeyThumbprint is the base64url SHA-256 JWK thumbprint of the DPoP proof key(RFC 9449 "jkt").
+	ExtraDPoPKeyThumbprint = "cnf.jkt"
+)
+
+const DPoPHeader = "DPoP"
+
+const (
+	AuditExtraTokenBinding      = "authn.tokenBinding"
+	AuditExtraTokenBindingError = "authn.tokenBindingError"
+)
+
+type TokenBindingMode string
+
+const (
+	// TokenBindingModeDisabled does not validate token binding.
+	TokenBindingModeDisabled TokenBindingMode = "Disabled"
+	// TokenBindingModeReportOnly validates token binding, failures are logged and audited but the request continues.
+	// It is used to roll out token binding before enforcing it.
+	TokenBindingModeReportOnly TokenBindingMode = "ReportOnly"
+	// TokenBindingModeEnforce rejects requests failing token binding validation.
+	TokenBindingModeEnforce TokenBindingMode = "Enforce"
+)
+
+type TokenBinding struct {
+	CertificateThumbprint string `json:"certificateThumbprint,omitempty"`
+	DPoPKeyThumbprint     string `json:"dpopKeyThumbprint,omitempty"`
+}
+
+func (b TokenBinding) IsZero() bool {
+	return b.CertificateThumbprint == "" && b.DPoPKeyThumbprint == ""
+}
+
+// SetTokenBinding sets the binding into the extra of the user.
+func SetTokenBinding(user *api.UserInfo, binding TokenBinding) {
+	if binding.IsZero() {
+		return
+	}
+	if user.Extra == nil {
+		user.Extra = map[string][]string{}
+	}
+	if binding.CertificateThumbprint != "" {
+		user.Extra[ExtraCertificateThumbprint] = []string{binding.CertificateThumbprint}
+	}
+	if binding.DPoPKeyThumbprint != "" {
+		user.Extra[ExtraDPoPKeyThumbprint] = []string{binding.DPoPKeyThumbprint}
+	}
+}
+
+// TokenBindingFromUserInfo returns the binding set by [SetTokenBinding].
+func TokenBindingFromUserInfo(user api.UserInfo) TokenBinding {
+	first := func(key string) string {
+		if values := user.Extra[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	return TokenBinding{
+		CertificateThumbprint: first(ExtraCertificateThumbprint),
+		DPoPKeyThumbprint:     first(ExtraDPoPKeyThumbprint),
+	}
+}
+
+// CertificateThumbprint returns the base64url SHA-256 thumbprint of the certificate.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+type TokenBindingOptions struct {
+	Mode TokenBindingMode `json:"mode,omitempty" description:"token binding mode, Disabled, ReportOnly or Enforce"`
+	// RequireBinding treats tokens without binding as failures.
+	RequireBinding bool `json:"requireBinding,omitempty" description:"treat tokens without binding as failures"`
+	// DPoPMaxAge is the max age of a DPoP proof by its "iat" claim.
+	DPoPMaxAge time.Duration `json:"dpopMaxAge,omitempty" description:"max age of a DPoP proof"`
+	// DPoPAlgorithms are the accepted signature algorithms of DPoP proofs.
+	DPoPAlgorithms []string `json:"dpopAlgorithms,omitempty" description:"accepted signature algorithms of DPoP proofs"`
+	// DPoPReplayCacheSize is the number of proof ids remembered to reject replayed proofs, 0 disables replay detection.
+	DPoPReplayCacheSize int `json:"dpopReplayCacheSize,omitempty" description:"number of DPoP proof ids remembered to reject replays"`
+	// DPoPExternalURL is the url clients reach the server by, e.g. "https://example.com/api" behind a proxy.
+	// The "htu" of DPoP proofs must be it joined with the path of the request,
+	// the host of the request is used if it is empty.
+	DPoPExternalURL string `json:"dpopExternalURL,omitempty" description:"external url of the server the htu of DPoP proofs is matched against, e.g. https://example.com/api behind a proxy"`
+}
+
+func NewDefaultTokenBindingOptions() *TokenBindingOptions {
+	return &TokenBindingOptions{
+		Mode:                TokenBindingModeDisabled,
+		DPoPMaxAge:          5 * time.Minute,
+		DPoPAlgorithms:      []string{string(jose.ES256), string(jose.ES384), string(jose.RS256), string(jose.PS256), string(jose.EdDSA)},
+		DPoPReplayCacheSize: 10000,
+	}
+}
+
+// NewTokenBindingFilter validates the token binding of the authenticated user,
+// it must be installed after the authentication filter.
+//
+// A certificate bound token requires the request presents the same client certificate over TLS.
+// A DPoP bound token requires a valid DPoP proof signed by the bound key for this request,
+// and must be sent by the DPoP authorization scheme, see [NewDPoPTokenAuthenticator].
+func NewTokenBindingFilter(options *TokenBindingOptions) api.Filter {
+	if options.Mode == "" || options.Mode == TokenBindingModeDisabled {
+		return api.FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			next.ServeHTTP(w, r)
+		})
+	}
+	validator := NewTokenBindingValidator(options)
+	return api.FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		user := api.AuthenticateFromContext(r.Context()).User
+		// requests without an authenticated user have no token to validate, e.g. public routes
+		if user.ID == "" && user.Name == "" || user.Name == api.AnonymousUser {
+			next.ServeHTTP(w, r)
+			return
+		}
+		binding := TokenBindingFromUserInfo(user)
+		err := validator.Validate(r, binding)
+		if err == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		api.AddAuditLogExtra(r, AuditExtraTokenBinding, string(options.Mode))
+		api.AddAuditLogExtra(r, AuditExtraTokenBindingError, err.Error())
+		log.FromContext(r.Context()).Info("token binding validation failed",
+			"mode", options.Mode, "path", r.URL.Path, "error", err.Error())
+		if options.Mode == TokenBindingModeReportOnly {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if binding.DPoPKeyThumbprint != "" {
+			w.Header().Set("WWW-Authenticate", `DPoP error="invalid_dpop_proof"`)
+		}
+		api.Unauthorized(w, fmt.Sprintf("Unauthorized: %v", err))
+	})
+}
+
+// NewDPoPTokenAuthenticator authenticates tokens sent by the DPoP authorization scheme(RFC 9449).
+// Only DPoP bound tokens are accepted, their proofs are validated by [NewTokenBindingFilter],
+// so the scheme is not accepted while token binding is disabled.
+func NewDPoPTokenAuthenticator(authn api.TokenAuthenticator, options *TokenBindingOptions) api.Authenticator {
+	return api.AuthenticateFunc(func(w http.ResponseWriter, r *http.Request) (*api.AuthenticateInfo, error) {
+		token := api.ExtractDPoPTokenFromRequest(r)
+		if token == "" || options.Mode == "" || options.Mode == TokenBindingModeDisabled {
+			return nil, api.ErrNotProvided
+		}
+		ctx := api.WithResponseHeader(r.Context(), w.Header())
+		info, err := authn.AuthenticateToken(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		if TokenBindingFromUserInfo(info.User).DPoPKeyThumbprint == "" {
+			return nil, fmt.Errorf("token sent by the DPoP scheme is not DPoP bound")
+		}
+		return info, nil
+	})
+}
+
+type TokenBindingValidator struct {
+	Options *TokenBindingOptions
+	seen    *expirable.LRU[string, struct{}]
+}
+
+func NewTokenBindingValidator(options *TokenBindingOptions) *TokenBindingValidator {
+	v := &TokenBindingValidator{Options: options}
+	if options.DPoPReplayCacheSize > 0 {
+		// a proof older than max age is rejected by "iat", so it is safe to forget it after twice max age
+		v.seen = expirable.NewLRU[string, struct{}](options.DPoPReplayCacheSize, nil, 2*options.DPoPMaxAge)
+	}
+	return v
+}
+
+// Validate validates the request satisfies the binding.
+func (v *TokenBindingValidator) Validate(r *http.Request, binding TokenBinding) error {
+	if binding.IsZero() {
+		if v.Options.RequireBinding {
+			return fmt.Errorf("token is not bound")
+		}
+		return nil
+	}
+	if binding.CertificateThumbprint != "" {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return fmt.Errorf("token is bound to a client certificate but none is presented")
+		}
+		if CertificateThumbprint(r.TLS.PeerCertificates[0]) != binding.CertificateThumbprint {
+			return fmt.Errorf("client certificate does not match the token binding")
+		}
+	}
+	if binding.DPoPKeyThumbprint != "" {
+		// a DPoP bound token must not be accepted as a bearer token, RFC 9449 section 7.1
+		if api.ExtractBearerTokenFromRequest(r) != "" {
+			return fmt.Errorf("DPoP bound token is sent as a bearer token")
+		}
+		thumbprint, err := v.ValidateDPoPProof(r)
+		if err != nil {
+			return err
+		}
+		if thumbprint != binding.DPoPKeyThumbprint {
+			return fmt.Errorf("DPoP proof key does not match the token binding")
+		}
+	}
+	return nil
+}
+
+type dpopClaims struct {
+	ID        string `json:"jti"`
+	Method    string `json:"htm"`
+	URL       string `json:"htu"`
+	IssuedAt  int64  `json:"iat"`
+	TokenHash string `json:"ath,omitempty"`
+}
+
+// ValidateDPoPProof validates the DPoP proof of the request(RFC 9449) and returns the thumbprint of the proof key.
+// The proof can also be used at sign in to get the key to bind a new session to.
+//
+// The "htu" claim is matched against [TokenBindingOptions.DPoPExternalURL] if it is set,
+// otherwise by the host and path of the request, the scheme is ignored as TLS is usually terminated by proxies.
+func (v *TokenBindingValidator) ValidateDPoPProof(r *http.Request) (string, error) {
+	proofs := r.Header.Values(DPoPHeader)
+	if len(proofs) != 1 {
+		return "", fmt.Errorf("exactly one DPoP proof is required")
+	}
+	algs := make([]jose.SignatureAlgorithm, 0, len(v.Options.DPoPAlgorithms))
+	for _, alg := range v.Options.DPoPAlgorithms {
+		algs = append(algs, jose.SignatureAlgorithm(alg))
+	}
+	jws, err := jose.ParseSignedCompact(proofs[0], algs)
+	if err != nil {
+		return "", fmt.Errorf("invalid DPoP proof: %w", err)
+	}
+	header := jws.Signatures[0].Protected
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); !strings.EqualFold(typ, "dpop+jwt") {
+		return "", fmt.Errorf("invalid DPoP proof type %q", typ)
+	}
+	if header.JSONWebKey == nil || !header.JSONWebKey.IsPublic() {
+		return "", fmt.Errorf("DPoP proof must carry a public key")
+	}
+	payload, err := jws.Verify(header.JSONWebKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid DPoP proof signature: %w", err)
+	}
+	claims := dpopClaims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("invalid DPoP proof claims: %w", err)
+	}
+	if claims.ID == "" {
+		return "", fmt.Errorf("DPoP proof has no jti")
+	}
+	if claims.Method != r.Method {
+		return "", fmt.Errorf("DPoP proof htm %q does not match the request", claims.Method)
+	}
+	if err := v.matchHTU(r, claims.URL); err != nil {
+		return "", err
+	}
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	if age := time.Since(issuedAt); age > v.Options.DPoPMaxAge || age < -time.Minute {
+		return "", fmt.Errorf("DPoP proof is expired or issued in the future")
+	}
+	if token := api.ExtractDPoPTokenFromRequest(r); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		if claims.TokenHash != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return "", fmt.Errorf("DPoP proof ath does not match the access token")
+		}
+	}
+	thumbprint, err := header.JSONWebKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	if v.seen != nil {
+		key := string(thumbprint) + "/" + claims.ID
+		if v.seen.Contains(key) {
+			return "", fmt.Errorf("DPoP proof is replayed")
+		}
+		v.seen.Add(key, struct{}{})
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+func (v *TokenBindingValidator) matchHTU(r *http.Request, claim string) error {
+	htu, err := url.Parse(claim)
+	if err != nil {
+		return fmt.Errorf("invalid DPoP proof htu %q: %w", claim, err)
+	}
+	scheme, host, path := htu.Scheme, r.Host, r.URL.Path
+	if v.Options.DPoPExternalURL != "" {
+		external, err := url.Parse(v.Options.DPoPExternalURL)
+		if err != nil {
+			return fmt.Errorf("invalid DPoP external url %q: %w", v.Options.DPoPExternalURL, err)
+		}
+		scheme, host, path = external.Scheme, external.Host, strings.TrimSuffix(external.Path, "/")+r.URL.Path
+	}
+	if !strings.EqualFold(htu.Scheme, scheme) || !strings.EqualFold(htu.Host, host) || htu.Path != path {
+		return fmt.Errorf("DPoP proof htu %q does not match the request", claim)
+	}
+	return nil
+}
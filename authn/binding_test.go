@@ -0,0 +1,378 @@
+package authn
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"xiaoshiai.cn/common/rest/api"
+)
+
+type dpopTestKey struct {
+	key        *ecdsa.PrivateKey
+	thumbprint string
+}
+
+func newDPoPTestKey(t *testing.T) dpopTestKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbprint, err := (&jose.JSONWebKey{Key: &key.PublicKey}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dpopTestKey{key: key, thumbprint: base64.RawURLEncoding.EncodeToString(thumbprint)}
+}
+
+func (k dpopTestKey) proof(t *testing.T, claims map[string]any) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.ES256, Key: k.key},
+		(&jose.SignerOptions{EmbedJWK: true}).WithType("dpop+jwt"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proof
+}
+
+func newTestCertificate(t *testing.T) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func accessTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestTokenBindingValidator(t *testing.T) {
+	key, other := newDPoPTestKey(t), newDPoPTestKey(t)
+	cert, otherCert := newTestCertificate(t), newTestCertificate(t)
+	claims := func(modify func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"jti": "id-" + time.Now().Format(time.RFC3339Nano),
+			"htm": http.MethodGet,
+			"htu": "https://example.com/api/v1/users",
+			"iat": time.Now().Unix(),
+			"ath": accessTokenHash("token"),
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		options func(*TokenBindingOptions)
+		binding TokenBinding
+		request func(r *http.Request)
+		wantErr string
+	}{
+		{
+			name: "not bound",
+		},
+		{
+			name:    "not bound but required",
+			options: func(o *TokenBindingOptions) { o.RequireBinding = true },
+			wantErr: "not bound",
+		},
+		{
+			name:    "certificate",
+			binding: TokenBinding{CertificateThumbprint: CertificateThumbprint(cert)},
+			request: func(r *http.Request) { r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}} },
+		},
+		{
+			name:    "certificate missing",
+			binding: TokenBinding{CertificateThumbprint: CertificateThumbprint(cert)},
+			wantErr: "none is presented",
+		},
+		{
+			name:    "certificate thumbprint mismatch",
+			binding: TokenBinding{CertificateThumbprint: CertificateThumbprint(cert)},
+			request: func(r *http.Request) { r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherCert}} },
+			wantErr: "does not match",
+		},
+		{
+			name:    "dpop",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) { r.Header.Set(DPoPHeader, key.proof(t, claims(nil))) },
+		},
+		{
+			name:    "dpop missing",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			wantErr: "exactly one DPoP proof",
+		},
+		{
+			name:    "dpop bad signature",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) {
+				parts := strings.Split(key.proof(t, claims(nil)), ".")
+				tampered := other.proof(t, claims(func(c map[string]any) { c["htm"] = http.MethodPost }))
+				parts[1] = strings.Split(tampered, ".")[1]
+				r.Header.Set(DPoPHeader, strings.Join(parts, "."))
+			},
+			wantErr: "signature",
+		},
+		{
+			name:    "dpop other key",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) { r.Header.Set(DPoPHeader, other.proof(t, claims(nil))) },
+			wantErr: "does not match the token binding",
+		},
+		{
+			name:    "dpop htm mismatch",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) {
+				r.Header.Set(DPoPHeader, key.proof(t, claims(func(c map[string]any) { c["htm"] = http.MethodPost })))
+			},
+			wantErr: "htm",
+		},
+		{
+			name:    "dpop htu mismatch",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) {
+				r.Header.Set(DPoPHeader, key.proof(t, claims(func(c map[string]any) { c["htu"] = "https://example.com/api/v1/groups" })))
+			},
+			wantErr: "htu",
+		},
+		{
+			name:    "dpop htu of external url",
+			options: func(o *TokenBindingOptions) { o.DPoPExternalURL = "https://public.example.com/prefix/" },
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) {
+				r.Header.Set(DPoPHeader, key.proof(t, claims(func(c map[string]any) { c["htu"] = "https://public.example.com/prefix/api/v1/users" })))
+			},
+		},
+		{
+			name:    "dpop htu of internal host behind proxy",
+			options: func(o *TokenBindingOptions) { o.DPoPExternalURL = "https://public.example.com/prefix" },
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) { r.Header.Set(DPoPHeader, key.proof(t, claims(nil))) },
+			wantErr: "htu",
+		},
+		{
+			name:    "dpop stale iat",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) {
+				r.Header.Set(DPoPHeader, key.proof(t, claims(func(c map[string]any) { c["iat"] = time.Now().Add(-time.Hour).Unix() })))
+			},
+			wantErr: "expired",
+		},
+		{
+			name:    "dpop future iat",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) {
+				r.Header.Set(DPoPHeader, key.proof(t, claims(func(c map[string]any) { c["iat"] = time.Now().Add(time.Hour).Unix() })))
+			},
+			wantErr: "future",
+		},
+		{
+			name:    "dpop ath mismatch",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) {
+				r.Header.Set(DPoPHeader, key.proof(t, claims(func(c map[string]any) { c["ath"] = accessTokenHash("other") })))
+			},
+			wantErr: "ath",
+		},
+		{
+			name:    "dpop bound sent as bearer",
+			binding: TokenBinding{DPoPKeyThumbprint: key.thumbprint},
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer token")
+				r.Header.Set(DPoPHeader, key.proof(t, claims(nil)))
+			},
+			wantErr: "sent as a bearer token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := NewDefaultTokenBindingOptions()
+			if tt.options != nil {
+				tt.options(options)
+			}
+			r := httptest.NewRequest(http.MethodGet, "https://example.com/api/v1/users", nil)
+			if tt.binding.DPoPKeyThumbprint != "" {
+				r.Header.Set("Authorization", "DPoP token")
+			} else {
+				r.Header.Set("Authorization", "Bearer token")
+			}
+			if tt.request != nil {
+				tt.request(r)
+			}
+			err := NewTokenBindingValidator(options).Validate(r, tt.binding)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want error contains %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokenBindingValidatorReplay(t *testing.T) {
+	key := newDPoPTestKey(t)
+	proof := key.proof(t, map[string]any{
+		"jti": "replayed",
+		"htm": http.MethodGet,
+		"htu": "https://example.com/",
+		"iat": time.Now().Unix(),
+	})
+	validator := NewTokenBindingValidator(NewDefaultTokenBindingOptions())
+	binding := TokenBinding{DPoPKeyThumbprint: key.thumbprint}
+	for i, wantErr := range []bool{false, true} {
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+		r.Header.Set(DPoPHeader, proof)
+		if err := validator.Validate(r, binding); (err != nil) != wantErr {
+			t.Errorf("request %d Validate() error = %v, want error %v", i, err, wantErr)
+		}
+	}
+}
+
+func TestTokenBindingFilter(t *testing.T) {
+	cert := newTestCertificate(t)
+	bound := api.UserInfo{Name: "alice"}
+	SetTokenBinding(&bound, TokenBinding{CertificateThumbprint: CertificateThumbprint(cert)})
+
+	tests := []struct {
+		name           string
+		mode           TokenBindingMode
+		requireBinding bool
+		user           *api.UserInfo
+		wantStatus     int
+	}{
+		{name: "enforce", mode: TokenBindingModeEnforce, user: &bound, wantStatus: http.StatusUnauthorized},
+		{name: "report only", mode: TokenBindingModeReportOnly, user: &bound, wantStatus: http.StatusOK},
+		{name: "disabled", mode: TokenBindingModeDisabled, user: &bound, wantStatus: http.StatusOK},
+		{name: "unbound required", mode: TokenBindingModeEnforce, requireBinding: true, user: &api.UserInfo{Name: "bob"}, wantStatus: http.StatusUnauthorized},
+		{name: "unauthenticated required", mode: TokenBindingModeEnforce, requireBinding: true, wantStatus: http.StatusOK},
+		{name: "anonymous required", mode: TokenBindingModeEnforce, requireBinding: true, user: &api.AnonymousUserInfo.User, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := NewDefaultTokenBindingOptions()
+			options.Mode, options.RequireBinding = tt.mode, tt.requireBinding
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			if tt.user != nil {
+				r = r.WithContext(api.WithAuthenticate(r.Context(), api.AuthenticateInfo{User: *tt.user}))
+			}
+			rec := httptest.NewRecorder()
+			NewTokenBindingFilter(options).Process(rec, r, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+type tokenAuthenticatorFunc func(ctx context.Context, token string) (*api.AuthenticateInfo, error)
+
+func (f tokenAuthenticatorFunc) AuthenticateToken(ctx context.Context, token string) (*api.AuthenticateInfo, error) {
+	return f(ctx, token)
+}
+
+func TestDPoPTokenAuthenticator(t *testing.T) {
+	bound := api.UserInfo{Name: "alice"}
+	SetTokenBinding(&bound, TokenBinding{DPoPKeyThumbprint: "jkt"})
+	tokens := tokenAuthenticatorFunc(func(ctx context.Context, token string) (*api.AuthenticateInfo, error) {
+		switch token {
+		case "bound":
+			return &api.AuthenticateInfo{User: bound}, nil
+		case "unbound":
+			return &api.AuthenticateInfo{User: api.UserInfo{Name: "bob"}}, nil
+		}
+		return nil, fmt.Errorf("invalid token")
+	})
+	tests := []struct {
+		name          string
+		mode          TokenBindingMode
+		authorization string
+		wantUser      string
+		wantErr       error
+	}{
+		{name: "bound", mode: TokenBindingModeEnforce, authorization: "DPoP bound", wantUser: "alice"},
+		{name: "report only", mode: TokenBindingModeReportOnly, authorization: "DPoP bound", wantUser: "alice"},
+		{name: "unbound", mode: TokenBindingModeEnforce, authorization: "DPoP unbound"},
+		{name: "invalid", mode: TokenBindingModeEnforce, authorization: "DPoP invalid"},
+		{name: "binding disabled", mode: TokenBindingModeDisabled, authorization: "DPoP bound", wantErr: api.ErrNotProvided},
+		{name: "bearer scheme", mode: TokenBindingModeEnforce, authorization: "Bearer bound", wantErr: api.ErrNotProvided},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := NewDefaultTokenBindingOptions()
+			options.Mode = tt.mode
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			r.Header.Set("Authorization", tt.authorization)
+			info, err := NewDPoPTokenAuthenticator(tokens, options).Authenticate(httptest.NewRecorder(), r)
+			if tt.wantUser != "" {
+				if err != nil || info.User.Name != tt.wantUser {
+					t.Fatalf("Authenticate() = %v, %v, want user %s", info, err, tt.wantUser)
+				}
+				return
+			}
+			if err == nil || tt.wantErr != nil && err != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBearerTokenAuthenticatorRejectsDPoPScheme(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	r.Header.Set("Authorization", "DPoP token")
+	if token := api.ExtractBearerTokenFromRequest(r); token != "" {
+		t.Errorf("ExtractBearerTokenFromRequest() = %q, want the DPoP scheme ignored", token)
+	}
+}
@@ -0,0 +1,303 @@
+package authn
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/meta"
+	"xiaoshiai.cn/common/openapi"
+	"xiaoshiai.cn/common/rest/api"
+	"xiaoshiai.cn/common/store"
+)
+
+// ProfileExtensionsField is the field of extensions in UserProfile,
+// extension fields are referenced as "extensions.<field>" in list options.
+const ProfileExtensionsField = "extensions"
+
+// ProfileSchema describes the custom attributes of user profiles of a deployment,
+// e.g. employee ID, department.
+type ProfileSchema struct {
+	// Version is the version of the schema, profiles with an older version are migrated by Migrations.
+	Version string `json:"version,omitempty"`
+	// Schema validates [UserProfile.Extensions].
+	Schema openapi.Schema `json:"schema,omitempty"`
+	// Indexes are extension fields(dotted path) which can be used in search, field selector and sort,
+	// providers should create indexes for them.
+	Indexes []string `json:"indexes,omitempty"`
+	// Migrations converts extensions of an older version to a newer version.
+	Migrations []ProfileMigration `json:"-"`
+}
+
+type ProfileMigrateFunc func(ctx context.Context, extensions map[string]any) (map[string]any, error)
+
+type ProfileMigration struct {
+	// From is the version of extensions to migrate, empty means extensions without a version.
+	From string
+	// To is the version after migration.
+	To      string
+	Migrate ProfileMigrateFunc
+}
+
+// Validate validates the extensions against the schema.
+func (s *ProfileSchema) Validate(ctx context.Context, extensions map[string]any) error {
+	if extensions == nil {
+		extensions = map[string]any{}
+	}
+	data, err := openapi.ConvertToJSONCompatible(extensions)
+	if err != nil {
+		return errors.NewBadRequest(fmt.Sprintf("invalid profile extensions: %v", err))
+	}
+	validator := openapi.NewDefaultValidator()
+	// extensions absent from a profile are checked by required only
+	validator.SkipAbsentProperties = true
+	output := validator.ValidateJsonContext(ctx, s.Schema, data)
+	if output.Valid {
+		return nil
+	}
+	return errors.NewBadRequest(fmt.Sprintf("invalid profile extensions: %s", strings.Join(outputMessages(output), "; ")))
+}
+
+// Migrate migrates the extensions of profile to the current version of the schema.
+// It returns true if the profile has been changed.
+// Migrations which do not lead to the current version, e.g. a cycle, are errors.
+func (s *ProfileSchema) Migrate(ctx context.Context, profile *UserProfile) (bool, error) {
+	if profile.ExtensionsVersion == s.Version {
+		return false, nil
+	}
+	version, extensions := profile.ExtensionsVersion, profile.Extensions
+	visited := map[string]bool{}
+	for version != s.Version {
+		if visited[version] {
+			return false, fmt.Errorf("cyclic migration of profile extensions at version %q", version)
+		}
+		visited[version] = true
+		idx := slices.IndexFunc(s.Migrations, func(m ProfileMigration) bool { return m.From == version })
+		if idx < 0 {
+			return false, fmt.Errorf("no migration of profile extensions from version %q to %q", version, s.Version)
+		}
+		migration := s.Migrations[idx]
+		if migration.Migrate != nil {
+			migrated, err := migration.Migrate(ctx, extensions)
+			if err != nil {
+				return false, fmt.Errorf("migrate profile extensions from version %q to %q: %w", migration.From, migration.To, err)
+			}
+			extensions = migrated
+		}
+		version = migration.To
+	}
+	profile.ExtensionsVersion, profile.Extensions = version, extensions
+	return true, nil
+}
+
+// IndexedFields returns fields of the indexes in list options, e.g. "extensions.department".
+func (s *ProfileSchema) IndexedFields() []string {
+	fields := make([]string, 0, len(s.Indexes))
+	for _, index := range s.Indexes {
+		fields = append(fields, ProfileExtensionsField+"."+index)
+	}
+	return fields
+}
+
+// CheckListOptions rejects list options which search, select or sort on extension fields not declared as indexes.
+func (s *ProfileSchema) CheckListOptions(options api.ListOptions) error {
+	fields := []string{}
+	for _, search := range meta.ParseSearch(options.Search) {
+		fields = append(fields, search.Field)
+	}
+	requirements, err := store.ParseRequirements(options.FieldSelector)
+	if err != nil {
+		return errors.NewBadRequest(fmt.Sprintf("invalid field selector: %v", err))
+	}
+	for _, requirement := range requirements {
+		fields = append(fields, requirement.Key)
+	}
+	for _, sort := range store.ParseSorts(options.Sort) {
+		fields = append(fields, sort.Field)
+	}
+	indexed := s.IndexedFields()
+	for _, field := range fields {
+		if !strings.HasPrefix(field, ProfileExtensionsField+".") {
+			continue
+		}
+		if !slices.Contains(indexed, field) {
+			return errors.NewBadRequest(fmt.Sprintf("profile field %s is not indexed", field))
+		}
+	}
+	return nil
+}
+
+func outputMessages(output openapi.OutPut) []string {
+	if len(output.Errors) == 0 {
+		if output.Message == "" {
+			return nil
+		}
+		if output.InstanceLocation == "" {
+			return []string{output.Message}
+		}
+		return []string{output.InstanceLocation + ": " + output.Message}
+	}
+	messages := []string{}
+	for _, e := range output.Errors {
+		messages = append(messages, outputMessages(e)...)
+	}
+	return messages
+}
+
+func NewProfileSchemaProvider(schema *ProfileSchema, provider Provider) *ProfileSchemaProvider {
+	return &ProfileSchemaProvider{Schema: schema, Provider: provider}
+}
+
+var _ Provider = &ProfileSchemaProvider{}
+
+// ProfileSchemaProvider validates and migrates profile extensions of the underlying provider.
+// Profiles are migrated on read, and saved with the current version on the next update.
+type ProfileSchemaProvider struct {
+	Schema *ProfileSchema
+	Provider
+}
+
+func (p *ProfileSchemaProvider) GetCurrentProfile(ctx context.Context, session string) (*UserProfile, error) {
+	profile, err := p.Provider.GetCurrentProfile(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.migrate(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (p *ProfileSchemaProvider) UpdateCurrentProfile(ctx context.Context, session string, data UserProfile) error {
+	if err := p.prepare(ctx, &data, false); err != nil {
+		return err
+	}
+	return p.Provider.UpdateCurrentProfile(ctx, session, data)
+}
+
+func (p *ProfileSchemaProvider) ListUsers(ctx context.Context, options ListUserOptions) (api.Page[UserProfile], error) {
+	if err := p.Schema.CheckListOptions(options.ListOptions); err != nil {
+		return api.Page[UserProfile]{}, err
+	}
+	page, err := p.Provider.ListUsers(ctx, options)
+	if err != nil {
+		return page, err
+	}
+	for i := range page.Items {
+		if err := p.migrate(ctx, &page.Items[i]); err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+func (p *ProfileSchemaProvider) CreateUser(ctx context.Context, user *UserProfile, password string) error {
+	if err := p.prepare(ctx, user, true); err != nil {
+		return err
+	}
+	return p.Provider.CreateUser(ctx, user, password)
+}
+
+func (p *ProfileSchemaProvider) GetUser(ctx context.Context, username string) (*UserProfile, error) {
+	profile, err := p.Provider.GetUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.migrate(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (p *ProfileSchemaProvider) UpdateUser(ctx context.Context, data *UserProfile) error {
+	if err := p.prepare(ctx, data, false); err != nil {
+		return err
+	}
+	return p.Provider.UpdateUser(ctx, data)
+}
+
+func (p *ProfileSchemaProvider) GetUserProfile(ctx context.Context, username string) (*UserProfile, error) {
+	profile, err := p.Provider.GetUserProfile(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.migrate(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (p *ProfileSchemaProvider) UpdateUserProfile(ctx context.Context, profile *UserProfile) error {
+	if err := p.prepare(ctx, profile, false); err != nil {
+		return err
+	}
+	return p.Provider.UpdateUserProfile(ctx, profile)
+}
+
+func (p *ProfileSchemaProvider) migrate(ctx context.Context, profile *UserProfile) error {
+	if _, err := p.Schema.Migrate(ctx, profile); err != nil {
+		return errors.NewInternalError(err)
+	}
+	return nil
+}
+
+// prepare migrates and validates extensions before saving.
+// on update, nil extensions means extensions are not changed.
+// Extensions without a version are written by clients of the current schema.
+func (p *ProfileSchemaProvider) prepare(ctx context.Context, profile *UserProfile, create bool) error {
+	if profile.Extensions == nil && !create {
+		return nil
+	}
+	if profile.ExtensionsVersion == "" {
+		profile.ExtensionsVersion = p.Schema.Version
+	}
+	if _, err := p.Schema.Migrate(ctx, profile); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+	return p.Schema.Validate(ctx, profile.Extensions)
+}
+
+// MigrateProfiles migrates all user profiles to the current version of the schema and saves them.
+// It returns the number of migrated profiles.
+// provider should be the underlying provider rather than a [ProfileSchemaProvider], which migrates on read.
+func MigrateProfiles(ctx context.Context, provider UserProvider, schema *ProfileSchema) (int, error) {
+	migrated := 0
+	// providers may not count the total, pages are listed until a short page or the last continue token
+	options := ListUserOptions{ListOptions: api.ListOptions{Page: 1, Size: 100}}
+	for {
+		list, err := provider.ListUsers(ctx, options)
+		if err != nil {
+			return migrated, err
+		}
+		for _, item := range list.Items {
+			profile, err := provider.GetUserProfile(ctx, item.Name)
+			if err != nil {
+				return migrated, err
+			}
+			changed, err := schema.Migrate(ctx, profile)
+			if err != nil {
+				return migrated, fmt.Errorf("user %s: %w", profile.Name, err)
+			}
+			if !changed {
+				continue
+			}
+			if err := schema.Validate(ctx, profile.Extensions); err != nil {
+				return migrated, fmt.Errorf("user %s: %w", profile.Name, err)
+			}
+			if err := provider.UpdateUserProfile(ctx, profile); err != nil {
+				return migrated, err
+			}
+			migrated++
+		}
+		switch {
+		case list.Continue != "":
+			options.Continue = list.Continue
+		case options.Continue != "" || len(list.Items) < options.Size:
+			return migrated, nil
+		default:
+			options.Page++
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package authn
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"xiaoshiai.cn/common/openapi"
+	"xiaoshiai.cn/common/rest/api"
+	"xiaoshiai.cn/common/store"
+)
+
+// renameDepartment migrates v1 extensions to v2 by renaming "dept" to "department".
+func renameDepartment(ctx context.Context, extensions map[string]any) (map[string]any, error) {
+	migrated := map[string]any{}
+	for k, v := range extensions {
+		if k == "dept" {
+			k = "department"
+		}
+		migrated[k] = v
+	}
+	return migrated, nil
+}
+
+func TestProfileSchemaMigrate(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations []ProfileMigration
+		profile    UserProfile
+		want       map[string]any
+		changed    bool
+		wantErr    string
+	}{
+		{
+			name:    "current version",
+			profile: UserProfile{ExtensionsVersion: "v2", Extensions: map[string]any{"dept": "rd"}},
+			want:    map[string]any{"dept": "rd"},
+		},
+		{
+			name: "chain",
+			migrations: []ProfileMigration{
+				{From: "v1", To: "v2", Migrate: renameDepartment},
+				{From: "", To: "v1"},
+			},
+			profile: UserProfile{Extensions: map[string]any{"dept": "rd"}},
+			want:    map[string]any{"department": "rd"},
+			changed: true,
+		},
+		{
+			name:       "missing migration",
+			migrations: []ProfileMigration{{From: "", To: "v1"}},
+			profile:    UserProfile{Extensions: map[string]any{"dept": "rd"}},
+			wantErr:    "no migration",
+		},
+		{
+			name:       "self migration",
+			migrations: []ProfileMigration{{From: "v1", To: "v1", Migrate: renameDepartment}},
+			profile:    UserProfile{ExtensionsVersion: "v1"},
+			wantErr:    "cyclic",
+		},
+		{
+			name: "cycle",
+			migrations: []ProfileMigration{
+				{From: "v0", To: "v1"},
+				{From: "v1", To: "v0"},
+			},
+			profile: UserProfile{ExtensionsVersion: "v0"},
+			wantErr: "cyclic",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := &ProfileSchema{Version: "v2", Migrations: tt.migrations}
+			profile := tt.profile
+			changed, err := schema.Migrate(context.Background(), &profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Migrate() error = %v, want error contains %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+			if changed != tt.changed || profile.ExtensionsVersion != "v2" || fmt.Sprint(profile.Extensions) != fmt.Sprint(tt.want) {
+				t.Errorf("Migrate() = %v, %s %v, want %v, v2 %v", changed, profile.ExtensionsVersion, profile.Extensions, tt.changed, tt.want)
+			}
+		})
+	}
+}
+
+// profileUserProvider keeps profiles in memory for the methods used by profile schemas.
+type profileUserProvider struct {
+	Provider
+	profiles []UserProfile
+	updated  []string
+	// continued lists by continue tokens without total, as providers backed by stores without counting do
+	continued bool
+}
+
+func (p *profileUserProvider) ListUsers(ctx context.Context, options ListUserOptions) (api.Page[UserProfile], error) {
+	if p.continued {
+		start := 0
+		if options.Continue != "" {
+			start, _ = strconv.Atoi(options.Continue)
+		}
+		end := min(start+options.Size, len(p.profiles))
+		page := api.Page[UserProfile]{Items: p.profiles[start:end], Size: options.Size}
+		if end < len(p.profiles) {
+			page.Continue = strconv.Itoa(end)
+		}
+		return page, nil
+	}
+	start := min((options.Page-1)*options.Size, len(p.profiles))
+	end := min(start+options.Size, len(p.profiles))
+	return api.Page[UserProfile]{Total: len(p.profiles), Items: p.profiles[start:end], Page: options.Page, Size: options.Size}, nil
+}
+
+func (p *profileUserProvider) GetUserProfile(ctx context.Context, username string) (*UserProfile, error) {
+	for _, profile := range p.profiles {
+		if profile.Name == username {
+			return &profile, nil
+		}
+	}
+	return nil, fmt.Errorf("user %s not found", username)
+}
+
+func (p *profileUserProvider) UpdateUserProfile(ctx context.Context, profile *UserProfile) error {
+	for i := range p.profiles {
+		if p.profiles[i].Name == profile.Name {
+			p.profiles[i] = *profile
+			p.updated = append(p.updated, profile.Name)
+			return nil
+		}
+	}
+	return fmt.Errorf("user %s not found", profile.Name)
+}
+
+func newTestProfileSchema() *ProfileSchema {
+	return &ProfileSchema{
+		Version: "v2",
+		Schema: openapi.Schema{
+			Type: spec.StringOrArray{"object"},
+			Properties: openapi.SchemaProperties{
+				{Name: "department", Schema: openapi.Schema{Type: spec.StringOrArray{"string"}}},
+			},
+		},
+		Migrations: []ProfileMigration{{From: "v1", To: "v2", Migrate: renameDepartment}},
+	}
+}
+
+func TestMigrateProfiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		count     int
+		continued bool
+		want      int
+	}{
+		{name: "pages", count: 250, want: 84},
+		{name: "full pages", count: 200, want: 67},
+		{name: "continue", count: 250, continued: true, want: 84},
+		{name: "continue full pages", count: 200, continued: true, want: 67},
+		{name: "empty", count: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &profileUserProvider{continued: tt.continued}
+			// every third profile is of the old version
+			for i := range tt.count {
+				profile := UserProfile{User: User{ObjectMeta: store.ObjectMeta{Name: fmt.Sprintf("user-%03d", i)}}}
+				if i%3 == 0 {
+					profile.ExtensionsVersion, profile.Extensions = "v1", map[string]any{"dept": "rd"}
+				} else {
+					profile.ExtensionsVersion, profile.Extensions = "v2", map[string]any{"department": "rd"}
+				}
+				provider.profiles = append(provider.profiles, profile)
+			}
+			migrated, err := MigrateProfiles(context.Background(), provider, newTestProfileSchema())
+			if err != nil {
+				t.Fatalf("MigrateProfiles() error = %v", err)
+			}
+			if migrated != tt.want || len(provider.updated) != tt.want {
+				t.Errorf("MigrateProfiles() = %d with %d updates, want %d", migrated, len(provider.updated), tt.want)
+			}
+			for _, profile := range provider.profiles {
+				if profile.ExtensionsVersion != "v2" || profile.Extensions["department"] != "rd" {
+					t.Fatalf("profile %s is not migrated: %s %v", profile.Name, profile.ExtensionsVersion, profile.Extensions)
+				}
+			}
+		})
+	}
+}
+
+func TestProfileSchemaProviderWrite(t *testing.T) {
+	underlying := &profileUserProvider{profiles: []UserProfile{{User: User{ObjectMeta: store.ObjectMeta{Name: "alice"}}}}}
+	provider := NewProfileSchemaProvider(newTestProfileSchema(), underlying)
+	ctx := context.Background()
+
+	// extensions without version are of the current schema, they are not migrated from an older version
+	profile := &UserProfile{User: User{ObjectMeta: store.ObjectMeta{Name: "alice"}}, Extensions: map[string]any{"department": "rd"}}
+	if err := provider.UpdateUserProfile(ctx, profile); err != nil {
+		t.Fatalf("UpdateUserProfile() error = %v", err)
+	}
+	if saved := underlying.profiles[0]; saved.ExtensionsVersion != "v2" || saved.Extensions["department"] != "rd" {
+		t.Errorf("saved profile %s %v, want the current version", saved.ExtensionsVersion, saved.Extensions)
+	}
+
+	// older versions are migrated before validation
+	profile = &UserProfile{User: User{ObjectMeta: store.ObjectMeta{Name: "alice"}}, ExtensionsVersion: "v1", Extensions: map[string]any{"dept": "ops"}}
+	if err := provider.UpdateUserProfile(ctx, profile); err != nil {
+		t.Fatalf("UpdateUserProfile() error = %v", err)
+	}
+	if saved := underlying.profiles[0]; saved.ExtensionsVersion != "v2" || saved.Extensions["department"] != "ops" {
+		t.Errorf("saved profile %s %v, want migrated", saved.ExtensionsVersion, saved.Extensions)
+	}
+
+	profile = &UserProfile{User: User{ObjectMeta: store.ObjectMeta{Name: "alice"}}, Extensions: map[string]any{"department": 1}}
+	if err := provider.UpdateUserProfile(ctx, profile); err == nil {
+		t.Errorf("UpdateUserProfile() with invalid extensions should fail")
+	}
+}
+
+func TestProfileSchemaValidate(t *testing.T) {
+	schema := newTestProfileSchema()
+	schema.Schema.Required = []string{"department"}
+	ctx := context.Background()
+	if err := schema.Validate(ctx, map[string]any{"department": "rd"}); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	// absent extensions are reported by required only
+	err := schema.Validate(ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), "department") || strings.Contains(err.Error(), "expected string") {
+		t.Errorf("Validate() of absent required extension error = %v", err)
+	}
+	schema.Schema.Required = nil
+	if err := schema.Validate(ctx, nil); err != nil {
+		t.Errorf("Validate() of absent optional extension error = %v", err)
+	}
+}
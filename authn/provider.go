@@ -181,6 +181,10 @@ type UserProfile struct {
 	PostalCode string    `json:"postalCode,omitempty"`
 	Languages  []string  `json:"languages,omitempty"`
 	MFA        MFAConfig `json:"mfa,omitempty"`
+	// Extensions are custom attributes of the deployment, validated by [ProfileSchema].
+	Extensions map[string]any `json:"extensions,omitempty"`
+	// ExtensionsVersion is the [ProfileSchema] version of Extensions.
+	ExtensionsVersion string `json:"extensionsVersion,omitempty"`
 }
 
 type UserProvider interface {
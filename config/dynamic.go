@@ -49,7 +49,7 @@ type StoreDynamicConfig struct {
 // List implements DynamicConfigUpdater.
 func (s *StoreDynamicConfig) List(ctx context.Context) (map[string]string, error) {
 	settings := &store.List[Setting]{}
-	if err := s.Storage.List(ctx, settings); err != nil {
+	if err := s.Storage.List(ctx, settings, store.WithoutSizePolicy()); err != nil {
 		return nil, store.IgnoreNotFound(err)
 	}
 	result := make(map[string]string, len(settings.Items))
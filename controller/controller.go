@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"sync"
 	"time"
@@ -127,6 +128,8 @@ type ControllerOptions[T comparable] struct {
 	Concurrent     int
 	LeaderElection LeaderElection
 	RateLimiter    workqueue.TypedRateLimiter[T]
+	// RestartPolicy handles panics of the reconciler, default to [DefaultRestartPolicy].
+	RestartPolicy RestartPolicy
 }
 
 type ControllerOption[T comparable] func(*ControllerOptions[T])
@@ -143,6 +146,12 @@ func WithLeaderElection[T comparable](leader LeaderElection) ControllerOption[T]
 	}
 }
 
+func WithRestartPolicy[T comparable](policy RestartPolicy) ControllerOption[T] {
+	return func(o *ControllerOptions[T]) {
+		o.RestartPolicy = policy
+	}
+}
+
 func NewController(name string, sync TypedReconciler[ScopedKey], options ...ControllerOption[ScopedKey]) *TypedController[ScopedKey] {
 	return NewTypedController(name, sync, options...)
 }
@@ -153,6 +162,9 @@ func NewTypedController[T comparable](name string, sync TypedReconciler[T], opti
 		opt(&opts)
 	}
 	opts.Concurrent = max(opts.Concurrent, 1)
+	if opts.RestartPolicy == (RestartPolicy{}) {
+		opts.RestartPolicy = DefaultRestartPolicy()
+	}
 	if sync == nil {
 		panic("sync function is required")
 	}
@@ -220,28 +232,51 @@ func (h *TypedController[T]) run(ctx context.Context) error {
 	}
 	// run queue consumer
 	eg.Go(func() error {
-		return RunQueueConsumer(ctx, h.queue, h.syncFunc.Reconcile, h.options.Concurrent)
+		return RunQueueConsumerWithOptions(ctx, h.queue, h.syncFunc.Reconcile, QueueConsumerOptions{
+			Name:          h.name,
+			Concurrent:    h.options.Concurrent,
+			RestartPolicy: h.options.RestartPolicy,
+		})
 	})
 	return eg.Wait()
 }
 
 func RunQueueConsumer[T comparable](ctx context.Context, queue TypedQueue[T], syncfunc func(ctx context.Context, key T) (Result, error), concurrent int) error {
+	return RunQueueConsumerWithOptions(ctx, queue, syncfunc, QueueConsumerOptions{Concurrent: concurrent, RestartPolicy: DefaultRestartPolicy()})
+}
+
+type QueueConsumerOptions struct {
+	// Name is used in logs and metrics, e.g. the controller name.
+	Name          string
+	Concurrent    int
+	RestartPolicy RestartPolicy
+}
+
+// RunQueueConsumerWithOptions processes items of the queue by syncfunc until ctx is done.
+// A panic in syncfunc is recovered and handled by the restart policy, it returns an error
+// once the panics exceed the limit of the policy.
+func RunQueueConsumerWithOptions[T comparable](ctx context.Context, queue TypedQueue[T], syncfunc func(ctx context.Context, key T) (Result, error), options QueueConsumerOptions) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 	go func() {
 		<-ctx.Done()
 		queue.ShutDown()
 	}()
 
 	log := log.FromContext(ctx)
+	panics := newPanicHandler(options.Name, options.RestartPolicy)
 
 	// 10 qps, burst 100
 	ratelimit := rate.NewLimiter(rate.Limit(10), 100)
 
+	concurrent := max(options.Concurrent, 1)
 	// get item from queue and process
 	wg := sync.WaitGroup{}
 	wg.Add(concurrent)
 	for range concurrent {
 		go func() {
 			defer wg.Done()
+			backoff := time.Duration(0)
 			for {
 				select {
 				case <-ctx.Done():
@@ -255,7 +290,24 @@ func RunQueueConsumer[T comparable](ctx context.Context, queue TypedQueue[T], sy
 					// ratelimit 1
 					ratelimit.Wait(ctx)
 
-					result, err := syncfunc(ctx, val)
+					result, recovered, err := syncWithRecover(ctx, val, syncfunc)
+					if recovered != nil {
+						queue.AddRateLimited(val)
+						queue.Done(val)
+						if err := panics.handle(ctx, val, recovered); err != nil {
+							cancel(err)
+							return
+						}
+						// restart the worker after backoff
+						backoff = panics.nextBackoff(backoff)
+						select {
+						case <-ctx.Done():
+							return
+						case <-time.After(backoff):
+						}
+						continue
+					}
+					backoff = 0
 					if err != nil {
 						log.Error(err, "sync", "key", val)
 						if result.Requeue {
@@ -283,5 +335,9 @@ func RunQueueConsumer[T comparable](ctx context.Context, queue TypedQueue[T], sy
 		}()
 	}
 	wg.Wait()
+	var limitErr *PanicLimitExceededError
+	if err := context.Cause(ctx); errors.As(err, &limitErr) {
+		return err
+	}
 	return nil
 }
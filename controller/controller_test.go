@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"xiaoshiai.cn/common/store"
 )
@@ -30,3 +33,72 @@ func Test_decodeScopes(t *testing.T) {
 		}
 	}
 }
+
+func TestRunQueueConsumerPanic(t *testing.T) {
+	policy := RestartPolicy{Backoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+	// always restart, other items are still processed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := NewDefaultTypedQueue[string]("test", nil)
+	queue.Add("panic")
+	queue.Add("ok")
+	done := make(chan struct{})
+	go func() {
+		err := RunQueueConsumerWithOptions(ctx, queue, func(ctx context.Context, key string) (Result, error) {
+			if key == "panic" {
+				panic("boom")
+			}
+			close(done)
+			return Result{}, nil
+		}, QueueConsumerOptions{Name: "test", Concurrent: 1, RestartPolicy: policy})
+		if err != nil {
+			t.Errorf("RunQueueConsumerWithOptions() error = %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("item after a panic is not processed")
+	}
+	cancel()
+
+	// stop after max panics
+	policy.MaxPanics = 2
+	queue = NewDefaultTypedQueue[string]("test", nil)
+	queue.Add("a")
+	queue.Add("b")
+	err := RunQueueConsumerWithOptions(context.Background(), queue, func(ctx context.Context, key string) (Result, error) {
+		panic("boom")
+	}, QueueConsumerOptions{Name: "test", Concurrent: 2, RestartPolicy: policy})
+	var limitErr *PanicLimitExceededError
+	if !errors.As(err, &limitErr) || limitErr.Panics != 2 {
+		t.Errorf("RunQueueConsumerWithOptions() error = %v, want panic limit exceeded", err)
+	}
+}
+
+func TestPanicHandlerWindow(t *testing.T) {
+	now := time.Now()
+	handler := newPanicHandler("test", RestartPolicy{MaxPanics: 3, PanicWindow: time.Minute})
+	handler.now = func() time.Time { return now }
+	recovered := &workerPanic{value: "boom"}
+	ctx := context.Background()
+
+	// panics spread out over more than the window never reach the limit
+	for range 10 {
+		if err := handler.handle(ctx, "a", recovered); err != nil {
+			t.Fatalf("handle() error = %v, want panics out of the window forgotten", err)
+		}
+		now = now.Add(40 * time.Second)
+	}
+	// panics within the window do
+	if err := handler.handle(ctx, "a", recovered); err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+	now = now.Add(time.Second)
+	err := handler.handle(ctx, "a", recovered)
+	var limitErr *PanicLimitExceededError
+	if !errors.As(err, &limitErr) || limitErr.Panics != 3 || limitErr.Window != time.Minute {
+		t.Errorf("handle() error = %v, want panic limit exceeded", err)
+	}
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"xiaoshiai.cn/common/log"
+)
+
+// RestartPolicy is the policy of queue consumer workers on panics of the reconciler.
+//
+// The panic is recovered, the item is requeued with rate limit and the worker restarts after a backoff,
+// so a panic on one item does not take down other workers or the process.
+type RestartPolicy struct {
+	// MaxPanics is the number of panics within PanicWindow allowed before the consumer stops with a [PanicLimitExceededError],
+	// which stops the controller, 0 always restarts the worker.
+	// Panics older than PanicWindow are forgotten, so rare panics of a long running controller do not add up to the limit.
+	MaxPanics int `json:"maxPanics,omitempty" description:"number of panics within the panic window allowed before the controller stops, 0 always restarts"`
+	// PanicWindow is the period panics are counted in for MaxPanics, default to 10 minutes.
+	PanicWindow time.Duration `json:"panicWindow,omitempty" description:"period panics are counted in for maxPanics, default to 10m"`
+	// Backoff is the delay before a worker restarts after a panic, doubled on consecutive panics of the worker.
+	Backoff time.Duration `json:"backoff,omitempty" description:"delay before a worker restarts after a panic"`
+	// MaxBackoff is the max delay before a worker restarts.
+	MaxBackoff time.Duration `json:"maxBackoff,omitempty" description:"max delay before a worker restarts after panics"`
+}
+
+// DefaultRestartPolicy always restarts the worker with backoff from 1s to 1m.
+func DefaultRestartPolicy() RestartPolicy {
+	return RestartPolicy{Backoff: time.Second, MaxBackoff: time.Minute, PanicWindow: defaultPanicWindow}
+}
+
+const defaultPanicWindow = 10 * time.Minute
+
+type PanicLimitExceededError struct {
+	Name   string
+	Panics int
+	Window time.Duration
+	Last   any
+}
+
+func (e *PanicLimitExceededError) Error() string {
+	return fmt.Sprintf("queue consumer %s stopped after %d panics in %s, last: %v", e.Name, e.Panics, e.Window, e.Last)
+}
+
+// workerPanic is a recovered panic with the stack where it happened.
+type workerPanic struct {
+	value any
+	stack []byte
+}
+
+// syncWithRecover calls syncfunc and returns the recovered panic if it panics.
+func syncWithRecover[T any](ctx context.Context, key T, syncfunc func(ctx context.Context, key T) (Result, error)) (result Result, recovered *workerPanic, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			recovered = &workerPanic{value: r, stack: debug.Stack()}
+		}
+	}()
+	result, err = syncfunc(ctx, key)
+	return result, nil, err
+}
+
+type panicHandler struct {
+	name    string
+	policy  RestartPolicy
+	counter metric.Int64Counter
+
+	mu sync.Mutex
+	// panics are the times of panics within the window
+	panics []time.Time
+	now    func() time.Time
+}
+
+func newPanicHandler(name string, policy RestartPolicy) *panicHandler {
+	meter := otel.Meter("xiaoshiai.cn/common/controller")
+	counter, err := meter.Int64Counter("controller.worker.panics",
+		metric.WithDescription("Number of panics recovered in controller workers."))
+	if err != nil {
+		otel.Handle(err)
+	}
+	if policy.PanicWindow <= 0 {
+		policy.PanicWindow = defaultPanicWindow
+	}
+	return &panicHandler{name: name, policy: policy, counter: counter, now: time.Now}
+}
+
+// handle records the panic on key, it returns an error if the panics within the window exceed the limit of the policy.
+func (p *panicHandler) handle(ctx context.Context, key any, recovered *workerPanic) error {
+	p.mu.Lock()
+	now := p.now()
+	p.panics = slices.DeleteFunc(p.panics, func(t time.Time) bool { return now.Sub(t) >= p.policy.PanicWindow })
+	p.panics = append(p.panics, now)
+	panics := len(p.panics)
+	p.mu.Unlock()
+
+	p.counter.Add(ctx, 1, metric.WithAttributes(attribute.String("controller", p.name)))
+	log.FromContext(ctx).Error(fmt.Errorf("panic: %v", recovered.value), "recovered panic in worker",
+		"key", key, "panics", panics, "stack", string(recovered.stack))
+
+	if p.policy.MaxPanics > 0 && panics >= p.policy.MaxPanics {
+		return &PanicLimitExceededError{Name: p.name, Panics: panics, Window: p.policy.PanicWindow, Last: recovered.value}
+	}
+	return nil
+}
+
+// nextBackoff returns the delay before the worker restarts, last is the previous delay of the worker.
+func (p *panicHandler) nextBackoff(last time.Duration) time.Duration {
+	next := max(last*2, p.policy.Backoff)
+	if p.policy.MaxBackoff > 0 {
+		next = min(next, p.policy.MaxBackoff)
+	}
+	return next
+}
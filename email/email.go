@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"mime"
 	"net/smtp"
 	"net/url"
 	"strings"
@@ -78,8 +79,10 @@ func (e *Email) Message() []byte {
 		fmt.Fprintf(&b, "Cc: %s\r\n", e.Cc.String())
 	}
 	fmt.Fprintf(&b, "Date: %s\r\n", e.Date.Format(time.RFC1123Z))
-	fmt.Fprintf(&b, "Subject: %s\r\n", e.Subject)
+	// non ASCII subjects, e.g. localized ones, are encoded as RFC 2047 requires
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", e.Subject))
 	if e.ContentType != "" {
+		fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
 		fmt.Fprintf(&b, "Content-Type: %s\r\n", e.ContentType)
 	}
 	fmt.Fprintf(&b, "\r\n")
@@ -0,0 +1,255 @@
+package email
+
+import (
+	"html/template"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// sanitizeAllowedTags are tags kept by [SanitizeHTML], with the attributes kept on them.
+	sanitizeAllowedTags = map[string][]string{
+		"a": {"href", "title"}, "b": nil, "strong": nil, "i": nil, "em": nil, "u": nil, "s": nil,
+		"p": nil, "br": nil, "hr": nil, "div": nil, "span": nil, "blockquote": nil, "code": nil, "pre": nil,
+		"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil, "ul": nil, "ol": nil, "li": nil,
+		"table": nil, "thead": nil, "tbody": nil, "tr": nil, "th": {"colspan", "rowspan"}, "td": {"colspan", "rowspan"},
+	}
+	// sanitizeDroppedTags are tags removed by [SanitizeHTML] with their content.
+	sanitizeDroppedTags = []string{
+		"script", "style", "head", "title", "iframe", "object", "embed", "template", "noscript", "textarea", "select", "svg", "math",
+	}
+	sanitizeURLSchemes = []string{"http", "https", "mailto"}
+	voidTags           = []string{"br", "hr", "img", "input", "meta", "link", "col", "wbr"}
+)
+
+// SanitizeHTML returns the html from untrusted sources with only basic formatting tags and links kept,
+// scripts, styles, event handlers and other attributes are removed. Unclosed tags are closed.
+func SanitizeHTML(s string) template.HTML {
+	z := html.NewTokenizer(strings.NewReader(s))
+	sb := &strings.Builder{}
+	opened := []string{}
+	dropping := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			for i := len(opened) - 1; i >= 0; i-- {
+				sb.WriteString("</" + opened[i] + ">")
+			}
+			return template.HTML(sb.String())
+		case html.TextToken:
+			if dropping == 0 {
+				sb.WriteString(html.EscapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if slices.Contains(sanitizeDroppedTags, token.Data) {
+				if tt == html.StartTagToken {
+					dropping++
+				}
+				continue
+			}
+			attrs, allowed := sanitizeAllowedTags[token.Data]
+			if dropping > 0 || !allowed {
+				continue
+			}
+			sb.WriteString("<" + token.Data)
+			for _, attr := range token.Attr {
+				if attr.Namespace != "" || !slices.Contains(attrs, attr.Key) {
+					continue
+				}
+				if attr.Key == "href" && !isSafeURL(attr.Val) {
+					continue
+				}
+				sb.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+			}
+			if token.Data == "a" {
+				sb.WriteString(` rel="noopener noreferrer"`)
+			}
+			sb.WriteString(">")
+			if tt == html.StartTagToken && !slices.Contains(voidTags, token.Data) {
+				opened = append(opened, token.Data)
+			}
+		case html.EndTagToken:
+			token := z.Token()
+			if slices.Contains(sanitizeDroppedTags, token.Data) {
+				if dropping > 0 {
+					dropping--
+				}
+				continue
+			}
+			if dropping > 0 {
+				continue
+			}
+			// close up to the matching tag, stray end tags are ignored
+			if i := slices.Index(opened, token.Data); i >= 0 {
+				for j := len(opened) - 1; j >= i; j-- {
+					sb.WriteString("</" + opened[j] + ">")
+				}
+				opened = opened[:i]
+			}
+		}
+	}
+}
+
+func isSafeURL(val string) bool {
+	u, err := url.Parse(strings.TrimSpace(val))
+	if err != nil {
+		return false
+	}
+	return slices.Contains(sanitizeURLSchemes, strings.ToLower(u.Scheme))
+}
+
+var (
+	textBlockTags     = []string{"div", "ul", "ol", "table", "tr", "blockquote", "pre", "section", "header", "footer", "article", "center"}
+	textParagraphTags = []string{"p", "h1", "h2", "h3", "h4", "h5", "h6", "hr"}
+	textSkippedTags   = []string{"head", "title", "script", "style", "template", "noscript"}
+)
+
+// HTMLToText returns the plain text alternative of the html email,
+// paragraphs are separated by blank lines, list items are prefixed by "- " and links are followed by their urls.
+func HTMLToText(s string) string {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		// html.Parse only fails on reader errors
+		return s
+	}
+	w := &textWriter{}
+	w.node(doc)
+
+	lines := strings.Split(w.sb.String(), "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		// at most one blank line in a row
+		if line == "" && (len(result) == 0 || result[len(result)-1] == "") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return strings.TrimSpace(strings.Join(result, "\n"))
+}
+
+type textWriter struct {
+	sb  strings.Builder
+	pre int
+}
+
+func (w *textWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+	tag := n.Data
+	switch {
+	case slices.Contains(textSkippedTags, tag):
+	case tag == "br":
+		w.sb.WriteString("\n")
+	case tag == "img":
+		w.text(attr(n, "alt"))
+	case tag == "li":
+		w.newlines(1)
+		w.sb.WriteString("- ")
+		w.children(n)
+		w.newlines(1)
+	case tag == "td" || tag == "th":
+		w.children(n)
+		w.sb.WriteString(" ")
+	case tag == "a":
+		start := w.sb.Len()
+		w.children(n)
+		text := strings.TrimSpace(w.sb.String()[start:])
+		href := strings.TrimPrefix(attr(n, "href"), "mailto:")
+		if href != "" && href != text && isSafeURL(attr(n, "href")) {
+			if text == "" {
+				w.text(href)
+			} else {
+				w.sb.WriteString(" (" + href + ")")
+			}
+		}
+	case tag == "pre":
+		w.newlines(1)
+		w.pre++
+		w.children(n)
+		w.pre--
+		w.newlines(1)
+	case slices.Contains(textParagraphTags, tag):
+		w.newlines(2)
+		w.children(n)
+		w.newlines(2)
+	case slices.Contains(textBlockTags, tag):
+		w.newlines(1)
+		w.children(n)
+		w.newlines(1)
+	default:
+		w.children(n)
+	}
+}
+
+func (w *textWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+// text writes s with whitespaces collapsed as browsers do, except in pre.
+func (w *textWriter) text(s string) {
+	if w.pre > 0 {
+		w.sb.WriteString(s)
+		return
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" && !w.atSpace() {
+			w.sb.WriteString(" ")
+		}
+		return
+	}
+	if isSpace(s[0]) && !w.atSpace() {
+		w.sb.WriteString(" ")
+	}
+	w.sb.WriteString(strings.Join(fields, " "))
+	if isSpace(s[len(s)-1]) {
+		w.sb.WriteString(" ")
+	}
+}
+
+// newlines ensures the text ends with at least n line breaks.
+func (w *textWriter) newlines(n int) {
+	content := strings.TrimRight(w.sb.String(), " ")
+	if content == "" {
+		return
+	}
+	w.sb.Reset()
+	w.sb.WriteString(content)
+	for i := len(content) - 1; i >= 0 && content[i] == '\n' && n > 0; i-- {
+		n--
+	}
+	w.sb.WriteString(strings.Repeat("\n", n))
+}
+
+func (w *textWriter) atSpace() bool {
+	s := w.sb.String()
+	return s == "" || isSpace(s[len(s)-1])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t' || c == '\r' || c == '\f'
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package email
+
+import (
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "basic formatting",
+			in:   `<p>Hello <b>Bob</b><br>welcome</p>`,
+			want: `<p>Hello <b>Bob</b><br>welcome</p>`,
+		},
+		{
+			name: "safe link",
+			in:   `<a href="https://example.com" title="home" target="_blank">home</a>`,
+			want: `<a href="https://example.com" title="home" rel="noopener noreferrer">home</a>`,
+		},
+		{
+			name: "javascript href",
+			in:   `<a href="javascript:alert(1)">click</a>`,
+			want: `<a rel="noopener noreferrer">click</a>`,
+		},
+		{
+			name: "javascript href with spaces and case",
+			in:   `<a href=" JavaScript:alert(1)">click</a>`,
+			want: `<a rel="noopener noreferrer">click</a>`,
+		},
+		{
+			name: "data href",
+			in:   `<a href="data:text/html;base64,PHNjcmlwdD4=">click</a>`,
+			want: `<a rel="noopener noreferrer">click</a>`,
+		},
+		{
+			name: "event handler attributes",
+			in:   `<p onclick="alert(1)" style="color:red">text</p><span onmouseover="x()">hover</span>`,
+			want: `<p>text</p><span>hover</span>`,
+		},
+		{
+			name: "script",
+			in:   `before<script>alert("<b>x</b>")</script>after`,
+			want: `beforeafter`,
+		},
+		{
+			name: "style",
+			in:   `<style>p { color: red }</style><p>text</p>`,
+			want: `<p>text</p>`,
+		},
+		{
+			name: "svg",
+			in:   `<svg onload="alert(1)"><circle r="1"/><a href="https://example.com">x</a></svg>ok`,
+			want: `ok`,
+		},
+		{
+			name: "disallowed tags keep text",
+			in:   `<img src="x" onerror="alert(1)"><font color="red">red</font>`,
+			want: `red`,
+		},
+		{
+			name: "unclosed tags",
+			in:   `<p><b>bold`,
+			want: `<p><b>bold</b></p>`,
+		},
+		{
+			name: "unbalanced end tags",
+			in:   `<p>a</b></div>b</p></p>`,
+			want: `<p>ab</p>`,
+		},
+		{
+			name: "misnested tags",
+			in:   `<b><i>text</b>after</i>`,
+			want: `<b><i>text</i></b>after`,
+		},
+		{
+			name: "text is escaped",
+			in:   `1 &lt; 2 &amp; "quoted"`,
+			want: `1 &lt; 2 &amp; &#34;quoted&#34;`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(SanitizeHTML(tt.in)); got != tt.want {
+				t.Errorf("SanitizeHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "paragraphs",
+			in: `<h1>Title</h1><p>First   line
+			continued</p><p>Second<br>line</p>`,
+			want: "Title\n\nFirst line continued\n\nSecond\nline",
+		},
+		{
+			name: "lists",
+			in:   `<p>Steps:</p><ul><li>one</li><li>two <b>bold</b></li></ul><ol><li>three</li></ol>`,
+			want: "Steps:\n\n- one\n- two bold\n- three",
+		},
+		{
+			name: "links",
+			in:   `<p>Open <a href="https://example.com/invite?id=1">the invitation</a> or <a href="https://example.com">https://example.com</a>, mail <a href="mailto:admin@example.com">admin@example.com</a>.</p>`,
+			want: "Open the invitation (https://example.com/invite?id=1) or https://example.com, mail admin@example.com.",
+		},
+		{
+			name: "unsafe links are not shown",
+			in:   `<a href="javascript:alert(1)">click</a>`,
+			want: "click",
+		},
+		{
+			name: "empty link text",
+			in:   `<a href="https://example.com"><img src="logo.png"></a>`,
+			want: "https://example.com",
+		},
+		{
+			name: "pre keeps whitespaces",
+			in:   "<p>Code:</p><pre>  a  b\n    c</pre><p>end</p>",
+			want: "Code:\n\n  a  b\n    c\n\nend",
+		},
+		{
+			name: "head, scripts and styles are skipped",
+			in:   `<html><head><title>Subject</title><style>p{}</style></head><body><script>x()</script><p>body</p></body></html>`,
+			want: "body",
+		},
+		{
+			name: "tables",
+			in:   `<table><tr><th>Name</th><th>Role</th></tr><tr><td>alice</td><td>admin</td></tr></table>`,
+			want: "Name Role\nalice admin",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTMLToText(tt.in); got != tt.want {
+				t.Errorf("HTMLToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package email
+
+import (
+	"context"
+	"net/http"
+
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/rest/api"
+)
+
+// TemplatesPreviewAPI serves rendered [Templates] with sample data,
+// so template authors and operators can review notifications without sending mails.
+// Install it behind authorization, samples may look like real data.
+type TemplatesPreviewAPI struct {
+	Templates *Templates
+	// Samples returns the sample data of the message, nil is used if it is nil.
+	Samples func(name string) any
+}
+
+func (a *TemplatesPreviewAPI) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	api.On(w, r, func(ctx context.Context) (any, error) {
+		return a.Templates.Names(), nil
+	})
+}
+
+func (a *TemplatesPreviewAPI) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	name := api.Path(r, "name", "")
+	var data any
+	if a.Samples != nil {
+		data = a.Samples(name)
+	}
+	rendered, err := a.Templates.Render(r.Context(), name, api.Query(r, "lang", ""), data)
+	if err != nil {
+		api.Error(w, err)
+		return
+	}
+	switch format := api.Query(r, "format", ""); format {
+	case "html":
+		api.RenderHTML(w, []byte(rendered.HTML))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		api.Raw(w, http.StatusOK, rendered.Text)
+	case "":
+		api.Success(w, rendered)
+	default:
+		api.Error(w, errors.NewBadRequest("unknown format "+format))
+	}
+}
+
+func (a *TemplatesPreviewAPI) Group() api.Group {
+	return api.NewGroup("/email-templates").
+		Tag("Email Templates").
+		Route(
+			api.GET("").
+				To(a.ListTemplates).
+				Doc("List email templates").
+				Response([]string{}),
+			api.GET("/{name}/preview").
+				To(a.PreviewTemplate).
+				Doc("Preview email template rendered with sample data").
+				Param(
+					api.QueryParam("lang", "language to render in").Optional(),
+					api.QueryParam("format", "render the html or text only").Optional().In("html", "text"),
+				).
+				Response(Rendered{}),
+		)
+}
@@ -0,0 +1,245 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"maps"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/i18n"
+)
+
+// DefaultLayout is the layout messages are rendered into unless they choose another one.
+const DefaultLayout = "default"
+
+// Templates renders notification emails, e.g. invitations and password resets, from html templates in a file system:
+//
+//	layouts/*.html  layouts, a layout renders the message by {{ template "content" . }}
+//	*.html          messages, a message defines "subject", "content" and optionally "text" and "layout"
+//
+// A message is rendered into [DefaultLayout], or the layout named by its "layout" template,
+// e.g. {{ define "layout" }}plain{{ end }}. It is rendered without layout if the layout does not exist.
+// The plain text alternative is generated from the html if the message does not define "text".
+//
+// Templates are executed by html/template, values are escaped by the context they appear in.
+// Use the "sanitize" function to embed html from users, e.g. the personal message of an invitation.
+//
+// Functions localize by the language of the render:
+//
+//	{{ t "invitation.title" }}                      Localizer.T
+//	{{ tf "invitation.body" (dict "name" .Name) }}  Localizer.Tf
+//	{{ p "invitation.days" .Days }}                 Localizer.P
+//	{{ date .ExpiresAt "long" }}                    Localizer.D, short, medium, long or full
+//	{{ lang }}                                      Localizer.Language
+type Templates struct {
+	// Manager provides localizers by language,
+	// the localizer of the context is used if it is nil.
+	Manager  i18n.Manager
+	messages map[string]*template.Template
+}
+
+// NewTemplates parses the layouts and messages in fsys, see [Templates].
+func NewTemplates(fsys fs.FS, manager i18n.Manager) (*Templates, error) {
+	base := template.New("").Funcs(templateFuncs(i18n.Default))
+	layouts, err := fs.Glob(fsys, "layouts/*.html")
+	if err != nil {
+		return nil, err
+	}
+	for _, filename := range layouts {
+		content, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(path.Base(filename), ".html")
+		if _, err := base.New(layoutTemplateName(name)).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("parse layout %s: %w", filename, err)
+		}
+	}
+	messages, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	t := &Templates{Manager: manager, messages: map[string]*template.Template{}}
+	for _, filename := range messages {
+		content, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tmpl.New(filename).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("parse message %s: %w", filename, err)
+		}
+		for _, required := range []string{"subject", "content"} {
+			if tmpl.Lookup(required) == nil {
+				return nil, fmt.Errorf("message %s does not define %q", filename, required)
+			}
+		}
+		t.messages[strings.TrimSuffix(filename, ".html")] = tmpl
+	}
+	return t, nil
+}
+
+// Names returns the names of the messages.
+func (t *Templates) Names() []string {
+	return slices.Sorted(maps.Keys(t.messages))
+}
+
+type Rendered struct {
+	Language string `json:"language,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	HTML     string `json:"html,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// Render renders the message in lang, the language of the context is used if lang is empty.
+// Without [Templates.Manager] it renders by the localizer of the context only,
+// a lang other than the language of it is rejected.
+// It sends nothing, the result can be previewed or verified in tests before [Rendered.Email].
+func (t *Templates) Render(ctx context.Context, name string, lang string, data any) (*Rendered, error) {
+	message, ok := t.messages[name]
+	if !ok {
+		return nil, errors.NewNotFound("email templates", name)
+	}
+	var localizer i18n.Localizer
+	if t.Manager != nil {
+		if lang == "" {
+			lang = i18n.LanguageFromContext(ctx)
+		}
+		localizer = t.Manager.GetLocalizer(lang)
+	} else {
+		localizer = i18n.FromContext(ctx)
+		if lang != "" && lang != localizer.Language() {
+			return nil, errors.NewBadRequest(fmt.Sprintf("can not render in language %s without a localization manager", lang))
+		}
+	}
+	// functions are bound to the localizer on a clone, the parsed templates are shared by renders
+	tmpl, err := message.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Funcs(templateFuncs(localizer))
+
+	execute := func(name string) (string, error) {
+		buf := &bytes.Buffer{}
+		if err := tmpl.ExecuteTemplate(buf, name, data); err != nil {
+			return "", fmt.Errorf("render %s: %w", name, err)
+		}
+		return buf.String(), nil
+	}
+	rendered := &Rendered{Language: localizer.Language()}
+	subject, err := execute("subject")
+	if err != nil {
+		return nil, err
+	}
+	// subject is a header, it is escaped as html by the template
+	rendered.Subject = strings.Join(strings.Fields(html.UnescapeString(subject)), " ")
+
+	layout := DefaultLayout
+	if tmpl.Lookup("layout") != nil {
+		if layout, err = execute("layout"); err != nil {
+			return nil, err
+		}
+		layout = strings.TrimSpace(layout)
+	}
+	body := "content"
+	if tmpl.Lookup(layoutTemplateName(layout)) != nil {
+		body = layoutTemplateName(layout)
+	}
+	if rendered.HTML, err = execute(body); err != nil {
+		return nil, err
+	}
+	if tmpl.Lookup("text") != nil {
+		text, err := execute("text")
+		if err != nil {
+			return nil, err
+		}
+		rendered.Text = strings.TrimSpace(html.UnescapeString(text))
+	} else {
+		rendered.Text = HTMLToText(rendered.HTML)
+	}
+	return rendered, nil
+}
+
+// Email returns a multipart/alternative email of the rendered text and html.
+func (r *Rendered) Email(from EmailAddress, to ...EmailAddress) *Email {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	// the text part goes first, clients prefer the last part they can display
+	for _, part := range []struct{ contentType, content string }{
+		{contentType: "text/plain; charset=utf-8", content: r.Text},
+		{contentType: "text/html; charset=utf-8", content: r.HTML},
+	} {
+		pw, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qw := quotedprintable.NewWriter(pw)
+		qw.Write([]byte(part.content))
+		qw.Close()
+	}
+	mw.Close()
+	return &Email{
+		From:        from,
+		To:          to,
+		Date:        time.Now(),
+		Subject:     r.Subject,
+		ContentType: "multipart/alternative; boundary=" + mw.Boundary(),
+		Body:        buf,
+	}
+}
+
+func layoutTemplateName(name string) string {
+	return "layouts/" + name
+}
+
+func templateFuncs(localizer i18n.Localizer) template.FuncMap {
+	return template.FuncMap{
+		"t":    localizer.T,
+		"tf":   localizer.Tf,
+		"p":    localizer.P,
+		"lang": localizer.Language,
+		"date": func(t time.Time, format string) (string, error) {
+			switch format {
+			case "short":
+				return localizer.D(t, i18n.DateFormatShort), nil
+			case "medium":
+				return localizer.D(t, i18n.DateFormatMedium), nil
+			case "long":
+				return localizer.D(t, i18n.DateFormatLong), nil
+			case "full":
+				return localizer.D(t, i18n.DateFormatFull), nil
+			default:
+				return "", fmt.Errorf("unknown date format %q", format)
+			}
+		},
+		"dict": func(kvs ...any) (map[string]any, error) {
+			if len(kvs)%2 != 0 {
+				return nil, fmt.Errorf("dict requires key value pairs")
+			}
+			dict := make(map[string]any, len(kvs)/2)
+			for i := 0; i < len(kvs); i += 2 {
+				key, ok := kvs[i].(string)
+				if !ok {
+					return nil, fmt.Errorf("dict key %v is not a string", kvs[i])
+				}
+				dict[key] = kvs[i+1]
+			}
+			return dict, nil
+		},
+		"sanitize": SanitizeHTML,
+	}
+}
@@ -0,0 +1,206 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/i18n"
+	"xiaoshiai.cn/common/rest/api"
+)
+
+var testTemplates = fstest.MapFS{
+	"layouts/default.html": {Data: []byte(`<html><body><div class="default">{{ template "content" . }}</div></body></html>`)},
+	"layouts/plain.html":   {Data: []byte(`<div class="plain">{{ template "content" . }}</div>`)},
+	"invitation.html": {Data: []byte(`
+{{ define "subject" }}  {{ t "invitation.subject" }} {{ .Org }}
+ & friends {{ end }}
+{{ define "content" }}<p>{{ t "invitation.body" }} <a href="{{ .Link }}">{{ .Org }}</a></p><blockquote>{{ sanitize .Message }}</blockquote>{{ end }}
+`)},
+	"reset.html": {Data: []byte(`
+{{ define "subject" }}Reset password{{ end }}
+{{ define "layout" }} plain {{ end }}
+{{ define "content" }}<p>Reset by {{ .Link }}</p>{{ end }}
+{{ define "text" }}Reset by {{ .Link }} &amp; ignore otherwise{{ end }}
+`)},
+	"bare.html": {Data: []byte(`
+{{ define "subject" }}Bare{{ end }}
+{{ define "layout" }}missing{{ end }}
+{{ define "content" }}<p>bare</p>{{ end }}
+`)},
+}
+
+type testData struct {
+	Org     string
+	Link    string
+	Message string
+}
+
+func newTestManager(t *testing.T) i18n.Manager {
+	manager := i18n.NewManager()
+	for lang, translations := range map[string]map[string]string{
+		"en":    {"invitation.subject": "Join", "invitation.body": "You are invited to"},
+		"zh-CN": {"invitation.subject": "加入", "invitation.body": "邀请您加入"},
+	} {
+		for key, value := range translations {
+			if err := manager.AddTranslation(lang, key, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return manager
+}
+
+func TestTemplatesRender(t *testing.T) {
+	templates, err := NewTemplates(testTemplates, newTestManager(t))
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+	data := testData{Org: "R&D", Link: "javascript:alert(1)", Message: `<b>welcome</b><script>x()</script>`}
+
+	rendered, err := templates.Render(context.Background(), "invitation", "zh-CN", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if rendered.Language != "zh-CN" {
+		t.Errorf("language = %q, want zh-CN", rendered.Language)
+	}
+	// subject is a header, it is unescaped and whitespaces are collapsed into one line
+	if rendered.Subject != "加入 R&D & friends" {
+		t.Errorf("subject = %q", rendered.Subject)
+	}
+	for _, want := range []string{`<div class="default">`, "邀请您加入", `href="#ZgotmplZ"`, "R&amp;D", "<b>welcome</b>"} {
+		if !strings.Contains(rendered.HTML, want) {
+			t.Errorf("html %q does not contain %q", rendered.HTML, want)
+		}
+	}
+	if strings.Contains(rendered.HTML, "<script>") {
+		t.Errorf("html %q contains script", rendered.HTML)
+	}
+	// text is generated from the html
+	if rendered.Text != "邀请您加入 R&D\n\nwelcome" {
+		t.Errorf("text = %q", rendered.Text)
+	}
+
+	// language of the context
+	ctx := context.WithValue(context.Background(), i18n.ContextKeyLanguage, "zh-CN")
+	if rendered, err := templates.Render(ctx, "invitation", "", data); err != nil || rendered.Subject != "加入 R&D & friends" {
+		t.Errorf("Render() in language of context = %v, %v", rendered, err)
+	}
+
+	if rendered, err := templates.Render(ctx, "invitation", "en", data); err != nil || rendered.Subject != "Join R&D & friends" {
+		t.Errorf("Render() in en = %v, %v", rendered, err)
+	}
+
+	// layout chosen by the message and the text template
+	rendered, err = templates.Render(context.Background(), "reset", "en", testData{Link: "https://example.com/reset?a=1&b=2"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.HasPrefix(rendered.HTML, `<div class="plain">`) {
+		t.Errorf("html = %q, want plain layout", rendered.HTML)
+	}
+	if rendered.Text != "Reset by https://example.com/reset?a=1&b=2 & ignore otherwise" {
+		t.Errorf("text = %q", rendered.Text)
+	}
+
+	// missing layout renders the content only
+	rendered, err = templates.Render(context.Background(), "bare", "en", nil)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if rendered.HTML != "<p>bare</p>" {
+		t.Errorf("html = %q, want content without layout", rendered.HTML)
+	}
+
+	if _, err := templates.Render(context.Background(), "unknown", "en", nil); !errors.IsNotFound(err) {
+		t.Errorf("Render() unknown message error = %v, want not found", err)
+	}
+}
+
+func TestTemplatesRenderWithoutManager(t *testing.T) {
+	templates, err := NewTemplates(testTemplates, nil)
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+	// the localizer of the context, i18n.Default without one
+	for _, lang := range []string{"", "en"} {
+		rendered, err := templates.Render(context.Background(), "invitation", lang, testData{Org: "R&D"})
+		if err != nil {
+			t.Fatalf("Render() in %q error = %v", lang, err)
+		}
+		if rendered.Language != "en" || rendered.Subject != "invitation.subject R&D & friends" {
+			t.Errorf("Render() in %q = %q %q", lang, rendered.Language, rendered.Subject)
+		}
+	}
+	if _, err := templates.Render(context.Background(), "invitation", "zh-CN", testData{}); !errors.IsCode(err, http.StatusBadRequest) {
+		t.Errorf("Render() in other language without manager error = %v, want bad request", err)
+	}
+}
+
+func TestNewTemplatesInvalid(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"missing subject": {"a.html": {Data: []byte(`{{ define "content" }}c{{ end }}`)}},
+		"missing content": {"a.html": {Data: []byte(`{{ define "subject" }}s{{ end }}`)}},
+		"invalid layout":  {"layouts/default.html": {Data: []byte(`{{ template }}`)}},
+	} {
+		if _, err := NewTemplates(fsys, nil); err == nil {
+			t.Errorf("NewTemplates() with %s should fail", name)
+		}
+	}
+}
+
+func TestTemplatesPreviewAPI(t *testing.T) {
+	templates, err := NewTemplates(testTemplates, newTestManager(t))
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+	preview := &TemplatesPreviewAPI{
+		Templates: templates,
+		Samples: func(name string) any {
+			return testData{Org: "R&D", Link: "https://example.com/" + name}
+		},
+	}
+	handler := api.New().Group(preview.Group()).Build()
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := serve("/email-templates")
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `["bare","invitation","reset"]` {
+		t.Errorf("list = %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serve("/email-templates/invitation/preview?lang=zh-CN")
+	rendered := Rendered{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &rendered); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("preview = %d %s, %v", rec.Code, rec.Body.String(), err)
+	}
+	if rendered.Subject != "加入 R&D & friends" || !strings.Contains(rendered.HTML, "https://example.com/invitation") {
+		t.Errorf("preview = %+v", rendered)
+	}
+
+	rec = serve("/email-templates/invitation/preview?format=html")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.HasPrefix(rec.Body.String(), "<html>") {
+		t.Errorf("preview html = %d %q %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	rec = serve("/email-templates/reset/preview?format=text")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") || rec.Body.String() != "Reset by https://example.com/reset & ignore otherwise" {
+		t.Errorf("preview text = %d %q %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	if rec = serve("/email-templates/invitation/preview?format=pdf"); rec.Code != http.StatusBadRequest {
+		t.Errorf("preview unknown format = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec = serve("/email-templates/unknown/preview"); rec.Code != http.StatusNotFound {
+		t.Errorf("preview unknown message = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -14,6 +14,9 @@ type localizer struct {
 	translations map[string]any
 	fallback     map[string]any
 	pluralRule   PluralRule
+
+	machineTranslated map[string]bool
+	marker            func(text string) string
 }
 
 func (l *localizer) T(key string, args ...any) string {
@@ -140,6 +143,11 @@ func (l *localizer) MustT(key string, args ...any) string {
 	return l.T(key, args...)
 }
 
+// IsMachineTranslated reports whether the translation of key is machine translated and not reviewed yet.
+func (l *localizer) IsMachineTranslated(key string) bool {
+	return l.machineTranslated[key]
+}
+
 // get retrieves a translation value by key, supporting nested keys.
 func (l *localizer) get(key string) string {
 	// Split key by dots for nested access
@@ -147,6 +155,9 @@ func (l *localizer) get(key string) string {
 
 	// Try main translations
 	if val := getNestedValue(l.translations, parts); val != "" {
+		if l.marker != nil && l.machineTranslated[key] {
+			return l.marker(val)
+		}
 		return val
 	}
 
@@ -2,14 +2,11 @@ package i18n
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
-
-	"sigs.k8s.io/yaml"
 )
 
 // manager implements the Manager interface.
@@ -18,14 +15,18 @@ type manager struct {
 	translations map[string]map[string]any // lang -> nested translations
 	fallbackLang string
 	pluralRules  map[string]PluralRule
+	// machineTranslated lang -> keys machine translated and not reviewed
+	machineTranslated map[string]map[string]bool
+	marker            func(text string) string
 }
 
 // NewManager creates a new i18n manager.
 func NewManager() Manager {
 	return &manager{
-		translations: make(map[string]map[string]any),
-		fallbackLang: "en",
-		pluralRules:  DefaultPluralRules(),
+		translations:      make(map[string]map[string]any),
+		fallbackLang:      "en",
+		pluralRules:       DefaultPluralRules(),
+		machineTranslated: make(map[string]map[string]bool),
 	}
 }
 
@@ -45,10 +46,12 @@ func (m *manager) GetLocalizer(lang string) Localizer {
 	}
 
 	return &localizer{
-		lang:         lang,
-		translations: trans,
-		fallback:     fallback,
-		pluralRule:   pluralRule,
+		lang:              lang,
+		translations:      trans,
+		fallback:          fallback,
+		pluralRule:        pluralRule,
+		machineTranslated: m.machineTranslated[lang],
+		marker:            m.marker,
 	}
 }
 
@@ -91,23 +94,16 @@ func (m *manager) LoadTranslations(dir string, format Format) error {
 }
 
 func (m *manager) LoadTranslationsFromBytes(lang string, data []byte, format Format) error {
-	var translations map[string]any
-
-	switch format {
-	case FormatJSON:
-		if err := json.Unmarshal(data, &translations); err != nil {
-			return fmt.Errorf("failed to unmarshal JSON: %w", err)
-		}
-	case FormatYAML:
-		if err := yaml.Unmarshal(data, &translations); err != nil {
-			return fmt.Errorf("failed to unmarshal YAML: %w", err)
-		}
-	default:
-		return fmt.Errorf("unsupported format: %s", format)
+	translations, err := unmarshalTranslations(data, format)
+	if err != nil {
+		return err
 	}
+	machineTranslated := machineTranslatedKeys(translations)
+	delete(translations, MachineTranslatedKey)
 
 	m.mu.Lock()
 	m.translations[lang] = translations
+	m.machineTranslated[lang] = machineTranslated
 	m.mu.Unlock()
 
 	return nil
@@ -117,6 +113,9 @@ func (m *manager) AddTranslation(lang, key, value string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// explicitly added translations are reviewed
+	delete(m.machineTranslated[lang], key)
+
 	if m.translations[lang] == nil {
 		m.translations[lang] = make(map[string]any)
 	}
@@ -151,6 +150,12 @@ func (m *manager) SetFallbackLanguage(lang string) {
 	m.mu.Unlock()
 }
 
+func (m *manager) SetMachineTranslatedMarker(marker func(text string) string) {
+	m.mu.Lock()
+	m.marker = marker
+	m.mu.Unlock()
+}
+
 func (m *manager) SupportedLanguages() []string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -0,0 +1,321 @@
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+// MachineTranslatedKey is the reserved top-level key of a translation file listing
+// keys which are machine translated and not reviewed yet.
+// Remove a key from the list after reviewing it.
+const MachineTranslatedKey = "$machineTranslated"
+
+// TranslationProvider translates a single message into another language.
+type TranslationProvider interface {
+	// Translate translates sourceText of the translation key from sourceLang into targetLang.
+	// key is a hint for context, e.g. "errors.not_found".
+	Translate(ctx context.Context, key, sourceText, sourceLang, targetLang string) (string, error)
+}
+
+// TranslationMemory remembers translations of source texts, so the same text is translated once
+// and reviewed translations are reused.
+type TranslationMemory interface {
+	Lookup(sourceLang, targetLang, sourceText string) (string, bool)
+	Store(sourceLang, targetLang, sourceText, targetText string)
+}
+
+// NewTranslationMemory creates an in-memory TranslationMemory.
+func NewTranslationMemory() TranslationMemory {
+	return &translationMemory{entries: make(map[string]string)}
+}
+
+type translationMemory struct {
+	mu      sync.RWMutex
+	entries map[string]string
+}
+
+func (m *translationMemory) Lookup(sourceLang, targetLang, sourceText string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	text, ok := m.entries[sourceLang+"\x00"+targetLang+"\x00"+sourceText]
+	return text, ok
+}
+
+func (m *translationMemory) Store(sourceLang, targetLang, sourceText, targetText string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[sourceLang+"\x00"+targetLang+"\x00"+sourceText] = targetText
+}
+
+// BootstrapOptions configures filling missing keys of a target locale.
+type BootstrapOptions struct {
+	Provider   TranslationProvider
+	SourceLang string
+	TargetLang string
+	// Memory is optional, reviewed translations of the target locale are added to it before translating.
+	Memory TranslationMemory
+}
+
+// BootstrapResult is the result of a bootstrap.
+type BootstrapResult struct {
+	// Translations is the target locale with missing keys filled.
+	Translations map[string]any
+	// Translated are keys filled by this bootstrap.
+	Translated []string
+	// Failed are keys which could not be translated, with the reason.
+	Failed map[string]string
+}
+
+var placeholderRegexp = regexp.MustCompile(`\{\{[^}]*\}\}|%[-+# 0]*\d*(?:\.\d+)?[a-zA-Z%]`)
+
+// Bootstrap fills keys of source missing in target by machine translation.
+// Filled keys are listed under [MachineTranslatedKey] of the result translations,
+// existing translations are never overwritten.
+// Translations changing the template or sprintf placeholders of the source text are rejected.
+func Bootstrap(ctx context.Context, source, target map[string]any, options BootstrapOptions) (*BootstrapResult, error) {
+	if options.Provider == nil {
+		return nil, fmt.Errorf("translation provider is required")
+	}
+	memory := options.Memory
+	if memory == nil {
+		memory = NewTranslationMemory()
+	}
+	result := &BootstrapResult{Translations: make(map[string]any), Failed: make(map[string]string)}
+	targetFlat := flattenTranslations(target, "")
+	sourceFlat := flattenTranslations(source, "")
+	machineTranslated := make(map[string]bool)
+	for key := range machineTranslatedKeys(target) {
+		// keys removed from the target are not translations any more
+		if _, ok := targetFlat[key]; ok {
+			machineTranslated[key] = true
+		}
+	}
+
+	// reviewed translations teach the memory
+	for key, text := range targetFlat {
+		if sourceText, ok := sourceFlat[key]; ok && !machineTranslated[key] {
+			memory.Store(options.SourceLang, options.TargetLang, sourceText, text)
+		}
+	}
+	for key, val := range target {
+		if key != MachineTranslatedKey {
+			result.Translations[key] = copyTranslationValue(val)
+		}
+	}
+
+	keys := make([]string, 0, len(sourceFlat))
+	for key := range sourceFlat {
+		if _, ok := targetFlat[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sourceText := sourceFlat[key]
+		translated, ok := memory.Lookup(options.SourceLang, options.TargetLang, sourceText)
+		if !ok {
+			text, err := options.Provider.Translate(ctx, key, sourceText, options.SourceLang, options.TargetLang)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				result.Failed[key] = err.Error()
+				continue
+			}
+			if !samePlaceholders(sourceText, text) {
+				result.Failed[key] = fmt.Sprintf("placeholders changed in translation %q", text)
+				continue
+			}
+			memory.Store(options.SourceLang, options.TargetLang, sourceText, text)
+			translated = text
+		}
+		setNestedValue(result.Translations, key, translated)
+		machineTranslated[key] = true
+		result.Translated = append(result.Translated, key)
+	}
+	if len(machineTranslated) > 0 {
+		list := make([]any, 0, len(machineTranslated))
+		for _, key := range slices.Sorted(maps.Keys(machineTranslated)) {
+			list = append(list, key)
+		}
+		result.Translations[MachineTranslatedKey] = list
+	}
+	return result, nil
+}
+
+// BootstrapLocale fills missing keys of the target locale file in dir from the source locale file,
+// e.g. "locales/en.json" into "locales/ja.json". The target file is created if not exists.
+func BootstrapLocale(ctx context.Context, dir string, format Format, options BootstrapOptions) (*BootstrapResult, error) {
+	source, err := readTranslationFile(filepath.Join(dir, options.SourceLang+"."+string(format)), format)
+	if err != nil {
+		return nil, err
+	}
+	targetFile := filepath.Join(dir, options.TargetLang+"."+string(format))
+	target, err := readTranslationFile(targetFile, format)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	result, err := Bootstrap(ctx, source, target, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Translated) == 0 {
+		return result, nil
+	}
+	var data []byte
+	switch format {
+	case FormatJSON:
+		// keep "<", ">" and "&" of translations readable
+		buf := &bytes.Buffer{}
+		encoder := json.NewEncoder(buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result.Translations)
+		data = buf.Bytes()
+	case FormatYAML:
+		data, err = yaml.Marshal(result.Translations)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(targetFile, data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write translation file %s: %w", targetFile, err)
+	}
+	return result, nil
+}
+
+func readTranslationFile(file string, format Format) (map[string]any, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	translations, err := unmarshalTranslations(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load translations from %s: %w", file, err)
+	}
+	return translations, nil
+}
+
+func unmarshalTranslations(data []byte, format Format) (map[string]any, error) {
+	var translations map[string]any
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal(data, &translations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &translations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YAML: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	return translations, nil
+}
+
+// machineTranslatedKeys returns keys listed under MachineTranslatedKey of translations.
+func machineTranslatedKeys(translations map[string]any) map[string]bool {
+	keys := make(map[string]bool)
+	list, _ := translations[MachineTranslatedKey].([]any)
+	for _, item := range list {
+		if key, ok := item.(string); ok {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// flattenTranslations flattens nested translations into dotted keys, non-string values are ignored.
+func flattenTranslations(translations map[string]any, prefix string) map[string]string {
+	flat := make(map[string]string)
+	for key, val := range translations {
+		if prefix == "" && key == MachineTranslatedKey {
+			continue
+		}
+		fullKey := key
+		if prefix != "" {
+			fullKey = prefix + "." + key
+		}
+		switch v := val.(type) {
+		case string:
+			flat[fullKey] = v
+		case map[string]any:
+			for k, s := range flattenTranslations(v, fullKey) {
+				flat[k] = s
+			}
+		}
+	}
+	return flat
+}
+
+func copyTranslationValue(val any) any {
+	nested, ok := val.(map[string]any)
+	if !ok {
+		return val
+	}
+	copied := make(map[string]any, len(nested))
+	for k, v := range nested {
+		copied[k] = copyTranslationValue(v)
+	}
+	return copied
+}
+
+func setNestedValue(translations map[string]any, key string, value string) {
+	parts := strings.Split(key, ".")
+	current := translations
+	for _, part := range parts[:len(parts)-1] {
+		nested, ok := current[part].(map[string]any)
+		if !ok {
+			nested = make(map[string]any)
+			current[part] = nested
+		}
+		current = nested
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+func samePlaceholders(source, translated string) bool {
+	a, b := placeholderRegexp.FindAllString(source, -1), placeholderRegexp.FindAllString(translated, -1)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// MachineTranslatedMarker is implemented by managers which can mark machine translated strings.
+type MachineTranslatedMarker interface {
+	// SetMachineTranslatedMarker sets a function to mark machine translated strings not reviewed yet,
+	// e.g. wrap them with "[MT] ", so they can be visually flagged. nil disables marking.
+	SetMachineTranslatedMarker(marker func(text string) string)
+}
+
+// SetMachineTranslatedMarker sets the marker of machine translated strings on m,
+// it returns false if m does not support marking.
+func SetMachineTranslatedMarker(m Manager, marker func(text string) string) bool {
+	if mt, ok := m.(MachineTranslatedMarker); ok {
+		mt.SetMachineTranslatedMarker(marker)
+		return true
+	}
+	return false
+}
+
+// IsMachineTranslated reports whether the translation of key in loc is machine translated and not reviewed yet.
+func IsMachineTranslated(loc Localizer, key string) bool {
+	if mt, ok := loc.(interface{ IsMachineTranslated(key string) bool }); ok {
+		return mt.IsMachineTranslated(key)
+	}
+	return false
+}
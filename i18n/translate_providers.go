@@ -0,0 +1,172 @@
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DeepLProvider translates with the DeepL API.
+type DeepLProvider struct {
+	// APIKey is the DeepL authentication key.
+	APIKey string
+	// BaseURL is "https://api.deepl.com", or "https://api-free.deepl.com" for free API keys.
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewDeepLProvider(apiKey string) *DeepLProvider {
+	baseURL := "https://api.deepl.com"
+	// keys of the free API end with ":fx"
+	if strings.HasSuffix(apiKey, ":fx") {
+		baseURL = "https://api-free.deepl.com"
+	}
+	return &DeepLProvider{APIKey: apiKey, BaseURL: baseURL}
+}
+
+func (p *DeepLProvider) Translate(ctx context.Context, key, sourceText, sourceLang, targetLang string) (string, error) {
+	req := map[string]any{
+		"text":        []string{sourceText},
+		"target_lang": strings.ToUpper(targetLang),
+		// DeepL source languages have no regional variants
+		"source_lang": strings.ToUpper(strings.Split(sourceLang, "-")[0]),
+		"context":     "translation key: " + key,
+	}
+	resp := struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}{}
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + p.APIKey}
+	if err := postJSON(ctx, p.Client, strings.TrimSuffix(p.BaseURL, "/")+"/v2/translate", headers, req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Translations) == 0 {
+		return "", fmt.Errorf("deepl: empty translation")
+	}
+	return resp.Translations[0].Text, nil
+}
+
+// GoogleProvider translates with the Google Cloud Translation API (v2).
+type GoogleProvider struct {
+	APIKey string
+	// BaseURL default to "https://translation.googleapis.com".
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewGoogleProvider(apiKey string) *GoogleProvider {
+	return &GoogleProvider{APIKey: apiKey, BaseURL: "https://translation.googleapis.com"}
+}
+
+func (p *GoogleProvider) Translate(ctx context.Context, key, sourceText, sourceLang, targetLang string) (string, error) {
+	req := map[string]any{
+		"q":      []string{sourceText},
+		"source": sourceLang,
+		"target": targetLang,
+		"format": "text",
+	}
+	resp := struct {
+		Data struct {
+			Translations []struct {
+				TranslatedText string `json:"translatedText"`
+			} `json:"translations"`
+		} `json:"data"`
+	}{}
+	headers := map[string]string{"X-Goog-Api-Key": p.APIKey}
+	if err := postJSON(ctx, p.Client, strings.TrimSuffix(p.BaseURL, "/")+"/language/translate/v2", headers, req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Data.Translations) == 0 {
+		return "", fmt.Errorf("google: empty translation")
+	}
+	return resp.Data.Translations[0].TranslatedText, nil
+}
+
+// OpenAIProvider translates with an OpenAI compatible chat completions API.
+type OpenAIProvider struct {
+	APIKey string
+	// BaseURL default to "https://api.openai.com/v1".
+	BaseURL string
+	Model   string
+	// Prompt is the system prompt, it is formatted with the source and target language.
+	Prompt string
+	Client *http.Client
+}
+
+const DefaultOpenAITranslatePrompt = "You are a professional software localization translator. " +
+	"Translate the user message from %s to %s. " +
+	"Keep placeholders such as {{.name}} and %%s unchanged. " +
+	"Reply with the translation only."
+
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return &OpenAIProvider{APIKey: apiKey, BaseURL: baseURL, Model: model, Prompt: DefaultOpenAITranslatePrompt}
+}
+
+func (p *OpenAIProvider) Translate(ctx context.Context, key, sourceText, sourceLang, targetLang string) (string, error) {
+	prompt := p.Prompt
+	if prompt == "" {
+		prompt = DefaultOpenAITranslatePrompt
+	}
+	req := map[string]any{
+		"model": p.Model,
+		"messages": []map[string]string{
+			{"role": "system", "content": fmt.Sprintf(prompt, sourceLang, targetLang)},
+			{"role": "user", "content": sourceText},
+		},
+		"temperature": 0,
+	}
+	resp := struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}{}
+	headers := map[string]string{"Authorization": "Bearer " + p.APIKey}
+	if err := postJSON(ctx, p.Client, strings.TrimSuffix(p.BaseURL, "/")+"/chat/completions", headers, req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("openai: empty translation")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+func postJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body, into any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("translate request failed: %s: %s", resp.Status, string(respBody))
+	}
+	return json.Unmarshal(respBody, into)
+}
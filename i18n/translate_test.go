@@ -0,0 +1,152 @@
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fakeProvider struct {
+	calls int
+}
+
+func (f *fakeProvider) Translate(ctx context.Context, key, sourceText, sourceLang, targetLang string) (string, error) {
+	f.calls++
+	if key == "broken" {
+		return "broken translation", nil
+	}
+	return fmt.Sprintf("[%s] %s", targetLang, sourceText), nil
+}
+
+func TestBootstrap(t *testing.T) {
+	source := map[string]any{
+		"hello":   "Hello",
+		"welcome": "Welcome, {{.name}}!",
+		"broken":  "Hi %s",
+		"user": map[string]any{
+			"name":  "Name",
+			"title": "Hello",
+		},
+	}
+	target := map[string]any{
+		"hello": "Bonjour",
+	}
+	provider := &fakeProvider{}
+	result, err := Bootstrap(context.Background(), source, target, BootstrapOptions{
+		Provider:   provider,
+		SourceLang: "en",
+		TargetLang: "fr",
+	})
+	if err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	if got := result.Translations["hello"]; got != "Bonjour" {
+		t.Errorf("existing translation should not be overwritten, got %v", got)
+	}
+	user := result.Translations["user"].(map[string]any)
+	if got := user["title"]; got != "Bonjour" {
+		t.Errorf("expected translation from memory 'Bonjour', got %v", got)
+	}
+	if got := result.Translations["welcome"]; got != "[fr] Welcome, {{.name}}!" {
+		t.Errorf("unexpected translation %v", got)
+	}
+	if _, ok := result.Failed["broken"]; !ok {
+		t.Errorf("translation with changed placeholders should fail")
+	}
+	if provider.calls != 3 {
+		t.Errorf("expected 3 provider calls, got %d", provider.calls)
+	}
+	want := []any{"user.name", "user.title", "welcome"}
+	if got := result.Translations[MachineTranslatedKey].([]any); !slices.Equal(got, want) {
+		t.Errorf("expected machine translated %v, got %v", want, got)
+	}
+}
+
+func TestBootstrapLocaleAndMarker(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{"hello":"Hello","bye":"Bye","terms":"Read <a href=\"{{.url}}\">terms</a> & conditions"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "de.json"), []byte(`{"hello":"Hallo"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := BootstrapLocale(context.Background(), dir, FormatJSON, BootstrapOptions{
+		Provider:   &fakeProvider{},
+		SourceLang: "en",
+		TargetLang: "de",
+	})
+	if err != nil {
+		t.Fatalf("BootstrapLocale failed: %v", err)
+	}
+
+	// markup of translations is written as is
+	data, err := os.ReadFile(filepath.Join(dir, "de.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"[de] Read <a href=\"{{.url}}\">terms</a> & conditions"`) {
+		t.Errorf("unexpected written translations %s", data)
+	}
+
+	mgr := NewManager()
+	if err := mgr.LoadTranslations(dir, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !SetMachineTranslatedMarker(mgr, func(text string) string { return "[MT] " + text }) {
+		t.Fatal("manager does not support marking machine translated strings")
+	}
+	loc := mgr.GetLocalizer("de")
+	if got := loc.T("hello"); got != "Hallo" {
+		t.Errorf("expected 'Hallo', got %q", got)
+	}
+	if got := loc.T("bye"); got != "[MT] [de] Bye" {
+		t.Errorf("expected marked translation, got %q", got)
+	}
+	if !IsMachineTranslated(loc, "bye") || IsMachineTranslated(loc, "hello") {
+		t.Errorf("unexpected machine translated flags")
+	}
+
+	// reviewed
+	if err := mgr.AddTranslation("de", "bye", "Tschüss"); err != nil {
+		t.Fatal(err)
+	}
+	if got := mgr.GetLocalizer("de").T("bye"); got != "Tschüss" {
+		t.Errorf("expected reviewed translation, got %q", got)
+	}
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		req := struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		content := strings.ToUpper(req.Messages[len(req.Messages)-1].Content)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"content": content}}},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(server.URL+"/v1", "key", "model")
+	got, err := provider.Translate(context.Background(), "hello", "hello", "en", "fr")
+	if err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+	if got != "HELLO" {
+		t.Errorf("expected 'HELLO', got %q", got)
+	}
+}
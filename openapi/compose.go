@@ -0,0 +1,571 @@
+package openapi
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SchemaBuilder builds a [Schema] fluently, e.g.
+//
+//	NewObjectSchema().
+//		Prop("name", String().MinLength(1)).
+//		Prop("replicas", Integer().Minimum(0)).
+//		Required("name").
+//		Build()
+type SchemaBuilder struct {
+	schema Schema
+}
+
+// SchemaFrom returns a builder starting from an existing schema.
+func SchemaFrom(schema Schema) *SchemaBuilder {
+	return &SchemaBuilder{schema: schema}
+}
+
+func NewObjectSchema() *SchemaBuilder {
+	return &SchemaBuilder{schema: Schema{Type: StringOrArray{SchemaTypeObject}}}
+}
+
+func String() *SchemaBuilder {
+	return &SchemaBuilder{schema: Schema{Type: StringOrArray{SchemaTypeString}}}
+}
+
+func Integer() *SchemaBuilder {
+	return &SchemaBuilder{schema: Schema{Type: StringOrArray{SchemaTypeInteger}}}
+}
+
+func Number() *SchemaBuilder {
+	return &SchemaBuilder{schema: Schema{Type: StringOrArray{SchemaTypeNumber}}}
+}
+
+func Boolean() *SchemaBuilder {
+	return &SchemaBuilder{schema: Schema{Type: StringOrArray{SchemaTypeBoolean}}}
+}
+
+// Array returns an array schema builder of items.
+func Array(items *SchemaBuilder) *SchemaBuilder {
+	b := &SchemaBuilder{schema: Schema{Type: StringOrArray{SchemaTypeArray}}}
+	if items != nil {
+		item := items.Build()
+		b.schema.Items = &item
+	}
+	return b
+}
+
+// Build returns the built schema.
+func (b *SchemaBuilder) Build() Schema {
+	return b.schema
+}
+
+// Prop sets the property, an existing property with the same name is replaced in place.
+func (b *SchemaBuilder) Prop(name string, prop *SchemaBuilder) *SchemaBuilder {
+	b.schema.Properties = setProperty(b.schema.Properties, name, prop.Build())
+	return b
+}
+
+// Required appends required properties.
+func (b *SchemaBuilder) Required(names ...string) *SchemaBuilder {
+	for _, name := range names {
+		if !slices.Contains(b.schema.Required, name) {
+			b.schema.Required = append(b.schema.Required, name)
+		}
+	}
+	return b
+}
+
+// AdditionalProperties sets the schema of additional properties, nil disallows additional properties.
+func (b *SchemaBuilder) AdditionalProperties(prop *SchemaBuilder) *SchemaBuilder {
+	if prop == nil {
+		b.schema.AdditionalProperties = &SchemaOrBool{Allows: false}
+		return b
+	}
+	additional := prop.Build()
+	b.schema.AdditionalProperties = &SchemaOrBool{Schema: &additional}
+	return b
+}
+
+func (b *SchemaBuilder) Title(title string) *SchemaBuilder {
+	b.schema.Title = title
+	return b
+}
+
+func (b *SchemaBuilder) Description(description string) *SchemaBuilder {
+	b.schema.Description = description
+	return b
+}
+
+func (b *SchemaBuilder) Default(value any) *SchemaBuilder {
+	b.schema.Default = value
+	return b
+}
+
+func (b *SchemaBuilder) Enum(values ...any) *SchemaBuilder {
+	b.schema.Enum = values
+	return b
+}
+
+func (b *SchemaBuilder) Format(format string) *SchemaBuilder {
+	b.schema.Format = format
+	return b
+}
+
+func (b *SchemaBuilder) Pattern(pattern string) *SchemaBuilder {
+	b.schema.Pattern = pattern
+	return b
+}
+
+func (b *SchemaBuilder) MinLength(n int64) *SchemaBuilder {
+	b.schema.MinLength = &n
+	return b
+}
+
+func (b *SchemaBuilder) MaxLength(n int64) *SchemaBuilder {
+	b.schema.MaxLength = &n
+	return b
+}
+
+func (b *SchemaBuilder) Minimum(n float64) *SchemaBuilder {
+	b.schema.Minimum = &n
+	return b
+}
+
+func (b *SchemaBuilder) Maximum(n float64) *SchemaBuilder {
+	b.schema.Maximum = &n
+	return b
+}
+
+func (b *SchemaBuilder) MinItems(n int64) *SchemaBuilder {
+	b.schema.MinItems = &n
+	return b
+}
+
+func (b *SchemaBuilder) MaxItems(n int64) *SchemaBuilder {
+	b.schema.MaxItems = &n
+	return b
+}
+
+func (b *SchemaBuilder) UniqueItems() *SchemaBuilder {
+	b.schema.UniqueItems = true
+	return b
+}
+
+func (b *SchemaBuilder) Nullable() *SchemaBuilder {
+	b.schema.Nullable = true
+	return b
+}
+
+func (b *SchemaBuilder) ReadOnly() *SchemaBuilder {
+	b.schema.ReadOnly = true
+	return b
+}
+
+// Extension sets an extension, e.g. "x-order".
+func (b *SchemaBuilder) Extension(name string, value any) *SchemaBuilder {
+	if b.schema.ExtraProps == nil {
+		b.schema.ExtraProps = map[string]any{}
+	}
+	b.schema.ExtraProps[name] = value
+	return b
+}
+
+func setProperty(props SchemaProperties, name string, schema Schema) SchemaProperties {
+	props = slices.Clone(props)
+	if i := slices.IndexFunc(props, func(p SchemaProperty) bool { return p.Name == name }); i >= 0 {
+		props[i].Schema = schema
+		return props
+	}
+	return append(props, SchemaProperty{Name: name, Schema: schema})
+}
+
+// Merge merges b into a, the result accepts only values valid against both of them.
+//
+// Conflict rules:
+//   - type: the intersection, "integer" satisfies "number". An empty intersection is a conflict.
+//   - properties: the union, a property in both is merged recursively. required and dependentRequired: the union.
+//     A property declared by one side only is merged with the additionalProperties schema of the other,
+//     or dropped if the other disallows additional properties. Requiring a dropped property is a conflict.
+//   - minimum/minLength/minItems/...: the larger one. maximum/maxLength/maxItems/...: the smaller one.
+//   - enum: the intersection. An empty intersection is a conflict.
+//   - allOf: concatenated. anyOf/oneOf/not in both: b's is appended to allOf.
+//   - annotations(title, description, default, examples) and extensions: b overrides a.
+//   - other keywords: the one set, setting both to different values is a conflict.
+func Merge(a, b Schema) (Schema, error) {
+	return merge("", a, b)
+}
+
+func merge(location string, a, b Schema) (Schema, error) {
+	out := a
+	conflict := func(keyword string, x, y any) error {
+		return fmt.Errorf("%s/%s: conflicting values %v and %v", location, keyword, x, y)
+	}
+
+	// type
+	if len(a.Type) > 0 && len(b.Type) > 0 {
+		types := intersectTypes(a.Type, b.Type)
+		if len(types) == 0 {
+			return out, conflict("type", a.Type, b.Type)
+		}
+		out.Type = types
+	} else if len(b.Type) > 0 {
+		out.Type = b.Type
+	}
+
+	// object
+	out.Properties = slices.Clone(a.Properties)
+	for _, prop := range b.Properties {
+		i := slices.IndexFunc(out.Properties, func(p SchemaProperty) bool { return p.Name == prop.Name })
+		if i < 0 {
+			out.Properties = append(out.Properties, prop)
+			continue
+		}
+		merged, err := merge(location+"/properties/"+jsonPointerEscape(prop.Name), out.Properties[i].Schema, prop.Schema)
+		if err != nil {
+			return out, err
+		}
+		out.Properties[i].Schema = merged
+	}
+	out.Required = slices.Clone(a.Required)
+	for _, name := range b.Required {
+		if !slices.Contains(out.Required, name) {
+			out.Required = append(out.Required, name)
+		}
+	}
+	// properties declared by one side only are additional properties of the other
+	props := SchemaProperties{}
+	for _, prop := range out.Properties {
+		propLocation := location + "/properties/" + jsonPointerEscape(prop.Name)
+		allowed := true
+		for _, other := range []Schema{a, b} {
+			if other.AdditionalProperties == nil || declaresProperty(other, prop.Name) {
+				continue
+			}
+			if additional := other.AdditionalProperties.Schema; additional != nil {
+				merged, err := merge(propLocation, prop.Schema, *additional)
+				if err != nil {
+					return out, err
+				}
+				prop.Schema = merged
+			} else if !other.AdditionalProperties.Allows {
+				allowed = false
+			}
+		}
+		if !allowed {
+			if slices.Contains(out.Required, prop.Name) {
+				return out, fmt.Errorf("%s: required property is not allowed by additionalProperties", propLocation)
+			}
+			continue
+		}
+		props = append(props, prop)
+	}
+	if out.Properties != nil {
+		out.Properties = props
+	}
+	if a.DependentRequired != nil || b.DependentRequired != nil {
+		out.DependentRequired = map[string][]string{}
+		for _, deps := range []map[string][]string{a.DependentRequired, b.DependentRequired} {
+			for name, required := range deps {
+				for _, dep := range required {
+					if !slices.Contains(out.DependentRequired[name], dep) {
+						out.DependentRequired[name] = append(out.DependentRequired[name], dep)
+					}
+				}
+			}
+		}
+	}
+	additional, err := mergeAdditional(location+"/additionalProperties", a.AdditionalProperties, b.AdditionalProperties)
+	if err != nil {
+		return out, err
+	}
+	out.AdditionalProperties = additional
+
+	// array
+	if a.Items != nil && b.Items != nil {
+		items, err := merge(location+"/items", *a.Items, *b.Items)
+		if err != nil {
+			return out, err
+		}
+		out.Items = &items
+	} else if b.Items != nil {
+		out.Items = b.Items
+	}
+
+	// bounds
+	out.MinLength = stricterMin(a.MinLength, b.MinLength)
+	out.MaxLength = stricterMax(a.MaxLength, b.MaxLength)
+	out.Minimum = stricterMin(a.Minimum, b.Minimum)
+	out.Maximum = stricterMax(a.Maximum, b.Maximum)
+	out.ExclusiveMinimum = stricterMin(a.ExclusiveMinimum, b.ExclusiveMinimum)
+	out.ExclusiveMaximum = stricterMax(a.ExclusiveMaximum, b.ExclusiveMaximum)
+	out.MinItems = stricterMin(a.MinItems, b.MinItems)
+	out.MaxItems = stricterMax(a.MaxItems, b.MaxItems)
+	out.MinProperties = stricterMin(a.MinProperties, b.MinProperties)
+	out.MaxProperties = stricterMax(a.MaxProperties, b.MaxProperties)
+	out.MinContains = stricterMin(a.MinContains, b.MinContains)
+	out.MaxContains = stricterMax(a.MaxContains, b.MaxContains)
+	if b.MultipleOf != nil {
+		if a.MultipleOf != nil && *a.MultipleOf != *b.MultipleOf {
+			return out, conflict("multipleOf", *a.MultipleOf, *b.MultipleOf)
+		}
+		out.MultipleOf = b.MultipleOf
+	}
+	out.UniqueItems = a.UniqueItems || b.UniqueItems
+	out.ReadOnly = a.ReadOnly || b.ReadOnly
+	out.WriteOnly = a.WriteOnly || b.WriteOnly
+	out.Deprecated = a.Deprecated || b.Deprecated
+	out.Nullable = a.Nullable && b.Nullable
+
+	// enum
+	if len(a.Enum) > 0 && len(b.Enum) > 0 {
+		enum := []any{}
+		for _, val := range a.Enum {
+			if slices.ContainsFunc(b.Enum, func(other any) bool { return valueEquals(val, other) }) {
+				enum = append(enum, val)
+			}
+		}
+		if len(enum) == 0 {
+			return out, conflict("enum", a.Enum, b.Enum)
+		}
+		out.Enum = enum
+	} else if len(b.Enum) > 0 {
+		out.Enum = b.Enum
+	}
+
+	// composition
+	out.AllOf = append(slices.Clone(a.AllOf), b.AllOf...)
+	if len(b.AnyOf) > 0 {
+		if len(a.AnyOf) > 0 {
+			out.AllOf = append(out.AllOf, Schema{AnyOf: b.AnyOf})
+		} else {
+			out.AnyOf = b.AnyOf
+		}
+	}
+	if len(b.OneOf) > 0 {
+		if len(a.OneOf) > 0 {
+			out.AllOf = append(out.AllOf, Schema{OneOf: b.OneOf})
+		} else {
+			out.OneOf = b.OneOf
+		}
+	}
+	if b.Not != nil {
+		if a.Not != nil {
+			out.AllOf = append(out.AllOf, Schema{Not: b.Not})
+		} else {
+			out.Not = b.Not
+		}
+	}
+
+	// annotations
+	if b.Title != "" {
+		out.Title = b.Title
+	}
+	if b.Description != "" {
+		out.Description = b.Description
+	}
+	if b.Default != nil {
+		out.Default = b.Default
+	}
+	if len(b.Examples) > 0 {
+		out.Examples = b.Examples
+	}
+	if b.Example != nil {
+		out.Example = b.Example
+	}
+	if len(a.ExtraProps) > 0 || len(b.ExtraProps) > 0 {
+		out.ExtraProps = maps.Clone(a.ExtraProps)
+		if out.ExtraProps == nil {
+			out.ExtraProps = map[string]any{}
+		}
+		maps.Copy(out.ExtraProps, b.ExtraProps)
+	}
+
+	// others
+	others := []struct {
+		keyword string
+		a, b    any
+		set     func(v any)
+	}{
+		{"$ref", a.Ref, b.Ref, func(v any) { out.Ref = v.(string) }},
+		{"const", a.Const, b.Const, func(v any) { out.Const = v }},
+		{"pattern", a.Pattern, b.Pattern, func(v any) { out.Pattern = v.(string) }},
+		{"format", a.Format, b.Format, func(v any) { out.Format = v.(string) }},
+		{"patternProperties", a.PatternProperties, b.PatternProperties, func(v any) { out.PatternProperties = v.(SchemaProperties) }},
+		{"propertyNames", a.PropertyNames, b.PropertyNames, func(v any) { out.PropertyNames = v.(*Schema) }},
+		{"prefixItems", a.PrefixItems, b.PrefixItems, func(v any) { out.PrefixItems = v.([]Schema) }},
+		{"contains", a.Contains, b.Contains, func(v any) { out.Contains = v.(*Schema) }},
+		{"if", a.If, b.If, func(v any) { out.If = v.(*Schema) }},
+		{"then", a.Then, b.Then, func(v any) { out.Then = v.(*Schema) }},
+		{"else", a.Else, b.Else, func(v any) { out.Else = v.(*Schema) }},
+		{"discriminator", a.Discriminator, b.Discriminator, func(v any) { out.Discriminator = v.(string) }},
+	}
+	for _, kw := range others {
+		if isZeroValue(kw.b) {
+			continue
+		}
+		if !isZeroValue(kw.a) && !reflect.DeepEqual(kw.a, kw.b) {
+			return out, conflict(kw.keyword, kw.a, kw.b)
+		}
+		kw.set(kw.b)
+	}
+	for _, kw := range []struct {
+		keyword string
+		a, b    map[string]Schema
+		set     func(v map[string]Schema)
+	}{
+		{"$defs", a.Defs, b.Defs, func(v map[string]Schema) { out.Defs = v }},
+		{"definitions", a.Definitions, b.Definitions, func(v map[string]Schema) { out.Definitions = v }},
+		{"dependentSchemas", a.DependentSchemas, b.DependentSchemas, func(v map[string]Schema) { out.DependentSchemas = v }},
+	} {
+		if len(kw.b) == 0 {
+			continue
+		}
+		merged := maps.Clone(kw.a)
+		if merged == nil {
+			merged = map[string]Schema{}
+		}
+		for name, schema := range kw.b {
+			if existing, ok := merged[name]; ok && !reflect.DeepEqual(existing, schema) {
+				return out, fmt.Errorf("%s/%s/%s: conflicting schemas", location, kw.keyword, jsonPointerEscape(name))
+			}
+			merged[name] = schema
+		}
+		kw.set(merged)
+	}
+	return out, nil
+}
+
+func isZeroValue(v any) bool {
+	return v == nil || reflect.ValueOf(v).IsZero()
+}
+
+func intersectTypes(a, b StringOrArray) StringOrArray {
+	types := StringOrArray{}
+	add := func(typ string) {
+		if !slices.Contains(types, typ) {
+			types = append(types, typ)
+		}
+	}
+	for _, x := range a {
+		for _, y := range b {
+			switch {
+			case x == y:
+				add(x)
+			case x == SchemaTypeInteger && y == SchemaTypeNumber, x == SchemaTypeNumber && y == SchemaTypeInteger:
+				add(SchemaTypeInteger)
+			}
+		}
+	}
+	return types
+}
+
+// declaresProperty returns true if name is a property or matches a pattern property of schema.
+func declaresProperty(schema Schema, name string) bool {
+	if slices.ContainsFunc(schema.Properties, func(p SchemaProperty) bool { return p.Name == name }) {
+		return true
+	}
+	return slices.ContainsFunc(schema.PatternProperties, func(p SchemaProperty) bool {
+		re, err := regexp.Compile(p.Name)
+		return err == nil && re.MatchString(name)
+	})
+}
+
+func mergeAdditional(location string, a, b *SchemaOrBool) (*SchemaOrBool, error) {
+	switch {
+	case a == nil:
+		return b, nil
+	case b == nil:
+		return a, nil
+	case !a.Allows && a.Schema == nil, !b.Allows && b.Schema == nil:
+		return &SchemaOrBool{Allows: false}, nil
+	case a.Schema != nil && b.Schema != nil:
+		merged, err := merge(location, *a.Schema, *b.Schema)
+		if err != nil {
+			return nil, err
+		}
+		return &SchemaOrBool{Schema: &merged}, nil
+	case a.Schema != nil:
+		return a, nil
+	default:
+		return b, nil
+	}
+}
+
+func stricterMin[T cmp.Ordered](a, b *T) *T {
+	if a == nil {
+		return b
+	}
+	if b == nil || *a >= *b {
+		return a
+	}
+	return b
+}
+
+func stricterMax[T cmp.Ordered](a, b *T) *T {
+	if a == nil {
+		return b
+	}
+	if b == nil || *a <= *b {
+		return a
+	}
+	return b
+}
+
+// WithRequired returns a copy of schema with required properties set to names,
+// names not in properties are ignored.
+// WithRequired(schema) derives a patch variant where all properties are optional.
+func WithRequired(schema Schema, names ...string) Schema {
+	out := schema
+	out.Required = nil
+	for _, name := range names {
+		if slices.ContainsFunc(schema.Properties, func(p SchemaProperty) bool { return p.Name == name }) &&
+			!slices.Contains(out.Required, name) {
+			out.Required = append(out.Required, name)
+		}
+	}
+	return out
+}
+
+// Subset returns a copy of schema with only the fields, in the order of the schema properties.
+// A field may be a dot separated path to keep part of a nested object, e.g. "spec.replicas".
+// Required properties and dependentRequired are filtered accordingly.
+func Subset(schema Schema, fields ...string) Schema {
+	whole := map[string]bool{}
+	nested := map[string][]string{}
+	for _, field := range fields {
+		name, rest, ok := strings.Cut(field, ".")
+		if !ok {
+			whole[name] = true
+			continue
+		}
+		nested[name] = append(nested[name], rest)
+	}
+	out := schema
+	out.Properties = nil
+	for _, prop := range schema.Properties {
+		switch {
+		case whole[prop.Name]:
+			out.Properties = append(out.Properties, prop)
+		case len(nested[prop.Name]) > 0:
+			out.Properties = append(out.Properties, SchemaProperty{Name: prop.Name, Schema: Subset(prop.Schema, nested[prop.Name]...)})
+		}
+	}
+	kept := func(name string) bool { return whole[name] || len(nested[name]) > 0 }
+	out.Required = nil
+	for _, name := range schema.Required {
+		if kept(name) {
+			out.Required = append(out.Required, name)
+		}
+	}
+	if schema.DependentRequired != nil {
+		out.DependentRequired = map[string][]string{}
+		for name, deps := range schema.DependentRequired {
+			if kept(name) {
+				out.DependentRequired[name] = slices.DeleteFunc(slices.Clone(deps), func(dep string) bool { return !kept(dep) })
+			}
+		}
+	}
+	return out
+}
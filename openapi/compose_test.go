@@ -0,0 +1,160 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaBuilder(t *testing.T) {
+	schema := NewObjectSchema().
+		Prop("name", String().MinLength(1)).
+		Prop("replicas", Integer().Minimum(0)).
+		Required("name").
+		Build()
+
+	v := NewDefaultValidator()
+	if out := v.ValidateJson(schema, map[string]any{"name": "foo", "replicas": float64(1)}); !out.Valid {
+		t.Errorf("expected valid, got %+v", out)
+	}
+	if out := v.ValidateJson(schema, map[string]any{"name": ""}); out.Valid {
+		t.Errorf("expected invalid on empty name")
+	}
+	if out := v.ValidateJson(schema, map[string]any{"replicas": float64(1)}); out.Valid {
+		t.Errorf("expected invalid on missing name")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := NewObjectSchema().
+		Prop("name", String().MaxLength(63)).
+		Prop("replicas", Number().Minimum(0)).
+		Required("name").
+		Build()
+
+	t.Run("compatible", func(t *testing.T) {
+		got, err := Merge(base, NewObjectSchema().
+			Prop("name", String().MinLength(1).MaxLength(253).Description("the name")).
+			Prop("replicas", Integer().Minimum(1)).
+			Prop("image", String()).
+			Required("image").
+			Build())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := NewObjectSchema().
+			Prop("name", String().MinLength(1).MaxLength(63).Description("the name")).
+			Prop("replicas", Integer().Minimum(1)).
+			Prop("image", String()).
+			Required("name", "image").
+			Build()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Merge() = %+v, want %+v", got, want)
+		}
+	})
+	t.Run("type conflict", func(t *testing.T) {
+		_, err := Merge(base, NewObjectSchema().Prop("name", Integer()).Build())
+		if err == nil || err.Error() != "/properties/name/type: conflicting values [string] and [integer]" {
+			t.Errorf("Merge() error = %v", err)
+		}
+	})
+	t.Run("enum conflict", func(t *testing.T) {
+		_, err := Merge(String().Enum("a", "b").Build(), String().Enum("c").Build())
+		if err == nil {
+			t.Errorf("Merge() expected conflict")
+		}
+	})
+	t.Run("pattern conflict", func(t *testing.T) {
+		_, err := Merge(String().Pattern("^a").Build(), String().Pattern("^b").Build())
+		if err == nil {
+			t.Errorf("Merge() expected conflict")
+		}
+	})
+	t.Run("dependent required", func(t *testing.T) {
+		a, b := base, base
+		a.DependentRequired = map[string][]string{"replicas": {"name"}}
+		b.DependentRequired = map[string][]string{"replicas": {"name", "image"}, "image": {"name"}}
+		got, err := Merge(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string][]string{"replicas": {"name", "image"}, "image": {"name"}}
+		if !reflect.DeepEqual(got.DependentRequired, want) {
+			t.Errorf("Merge() dependentRequired = %v, want %v", got.DependentRequired, want)
+		}
+		if err := ValidateSchema(&got, map[string]any{"name": "a", "replicas": 1.0}); err == nil {
+			t.Errorf("merged schema accepts a value missing a dependent required property of b")
+		}
+	})
+	t.Run("closed", func(t *testing.T) {
+		closed := NewObjectSchema().Prop("name", String()).AdditionalProperties(nil).Build()
+		got, err := Merge(closed, NewObjectSchema().Prop("name", String().MaxLength(63)).Prop("image", String()).Build())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := NewObjectSchema().Prop("name", String().MaxLength(63)).AdditionalProperties(nil).Build()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Merge() = %+v, want %+v", got, want)
+		}
+		// the other way around
+		got, err = Merge(NewObjectSchema().Prop("image", String()).Build(), closed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Properties) != 1 || got.Properties[0].Name != "name" {
+			t.Errorf("Merge() properties = %+v, want only name", got.Properties)
+		}
+		_, err = Merge(closed, NewObjectSchema().Prop("image", String()).Required("image").Build())
+		if err == nil || err.Error() != "/properties/image: required property is not allowed by additionalProperties" {
+			t.Errorf("Merge() error = %v", err)
+		}
+	})
+	t.Run("additional properties schema", func(t *testing.T) {
+		typed := NewObjectSchema().Prop("name", String()).AdditionalProperties(Integer()).Build()
+		got, err := Merge(typed, NewObjectSchema().Prop("replicas", Number().Minimum(0)).Build())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := NewObjectSchema().Prop("name", String()).Prop("replicas", Integer().Minimum(0)).AdditionalProperties(Integer()).Build()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Merge() = %+v, want %+v", got, want)
+		}
+		if _, err := Merge(typed, NewObjectSchema().Prop("image", String()).Build()); err == nil {
+			t.Errorf("Merge() expected conflict with the additionalProperties schema")
+		}
+	})
+}
+
+func TestSubset(t *testing.T) {
+	schema := NewObjectSchema().
+		Prop("name", String()).
+		Prop("spec", NewObjectSchema().
+			Prop("replicas", Integer()).
+			Prop("image", String()).
+			Required("replicas", "image")).
+		Prop("status", NewObjectSchema()).
+		Required("name", "spec").
+		Build()
+
+	got := Subset(schema, "spec.replicas", "name")
+	want := NewObjectSchema().
+		Prop("name", String()).
+		Prop("spec", NewObjectSchema().
+			Prop("replicas", Integer()).
+			Required("replicas")).
+		Required("name", "spec").
+		Build()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Subset() = %+v, want %+v", got, want)
+	}
+	if len(schema.Properties) != 3 || len(schema.Properties[1].Schema.Properties) != 2 {
+		t.Errorf("Subset() should not modify the original schema")
+	}
+
+	patch := WithRequired(got)
+	if len(patch.Required) != 0 || len(got.Required) != 2 {
+		t.Errorf("WithRequired() = %v, original %v", patch.Required, got.Required)
+	}
+	if got := WithRequired(schema, "status", "unknown").Required; !reflect.DeepEqual(got, []string{"status"}) {
+		t.Errorf("WithRequired() = %v", got)
+	}
+}
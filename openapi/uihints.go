@@ -0,0 +1,193 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// XUI is the extension holds [UIHints] for form generation.
+const XUI = "x-ui"
+
+type UIWidget string
+
+const (
+	UIWidgetInput    UIWidget = "input"
+	UIWidgetTextarea UIWidget = "textarea"
+	UIWidgetPassword UIWidget = "password"
+	UIWidgetNumber   UIWidget = "number"
+	UIWidgetSwitch   UIWidget = "switch"
+	UIWidgetSelect   UIWidget = "select"
+	UIWidgetRadio    UIWidget = "radio"
+	UIWidgetCheckbox UIWidget = "checkbox"
+	UIWidgetDate     UIWidget = "date"
+	UIWidgetDateTime UIWidget = "datetime"
+	UIWidgetCode     UIWidget = "code"
+	UIWidgetKeyValue UIWidget = "keyvalue"
+	UIWidgetFile     UIWidget = "file"
+)
+
+// UIWidgets are the widgets accepted by [UIHintsMetaSchema].
+// Append to it on init to accept custom widgets.
+var UIWidgets = []UIWidget{
+	UIWidgetInput, UIWidgetTextarea, UIWidgetPassword, UIWidgetNumber, UIWidgetSwitch,
+	UIWidgetSelect, UIWidgetRadio, UIWidgetCheckbox, UIWidgetDate, UIWidgetDateTime,
+	UIWidgetCode, UIWidgetKeyValue, UIWidgetFile,
+}
+
+// UIHints are hints for UIs generating forms from the schema,
+// they do not change the validation of data.
+type UIHints struct {
+	// Widget is the widget renders the field, UIs choose one by type if empty.
+	Widget UIWidget `json:"widget,omitempty"`
+	// Order is the order of the field among its siblings, smaller first.
+	Order int `json:"order,omitempty"`
+	// Group is the group the field is rendered in, e.g. "Advanced".
+	Group       string `json:"group,omitempty"`
+	Placeholder string `json:"placeholder,omitempty"`
+	// Help is a short help text shown next to the field, description is used if empty.
+	Help     string `json:"help,omitempty"`
+	Hidden   bool   `json:"hidden,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// Options are widget specific options, e.g. {"language": "yaml"} for the code widget.
+	Options map[string]any `json:"options,omitempty"`
+}
+
+// UIHintsMetaSchema returns the schema of the x-ui extension.
+func UIHintsMetaSchema() Schema {
+	widgets := make([]any, 0, len(UIWidgets))
+	for _, widget := range UIWidgets {
+		widgets = append(widgets, string(widget))
+	}
+	schema := NewObjectSchema().
+		Prop("widget", String().Enum(widgets...)).
+		Prop("order", Integer()).
+		Prop("group", String()).
+		Prop("placeholder", String()).
+		Prop("help", String()).
+		Prop("hidden", Boolean()).
+		Prop("disabled", Boolean()).
+		Prop("options", NewObjectSchema()).
+		Build()
+	// unknown keys are likely typos
+	schema.AdditionalProperties = &SchemaOrBool{Allows: false}
+	return schema
+}
+
+// GetUIHints returns the x-ui hints of the schema, nil if not set.
+func (s *Schema) GetUIHints() (*UIHints, error) {
+	val, ok := s.ExtraProps[XUI]
+	if !ok || val == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	hints := &UIHints{}
+	if err := json.Unmarshal(data, hints); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", XUI, err)
+	}
+	return hints, nil
+}
+
+// SetUIHints sets the x-ui hints of the schema, nil removes them.
+// The hints are stored in the JSON form, the same as unmarshaled from a schema document.
+func (s *Schema) SetUIHints(hints *UIHints) {
+	if hints == nil {
+		delete(s.ExtraProps, XUI)
+		return
+	}
+	val, err := ConvertToJSONCompatible(hints)
+	if err != nil {
+		// UIHints always marshals
+		panic(err)
+	}
+	if s.ExtraProps == nil {
+		s.ExtraProps = map[string]any{}
+	}
+	s.ExtraProps[XUI] = val
+}
+
+// UI sets the x-ui hints.
+func (b *SchemaBuilder) UI(hints UIHints) *SchemaBuilder {
+	b.schema.SetUIHints(&hints)
+	return b
+}
+
+// ValidateUIHints validates the x-ui hints of the schema and all its subschemas against [UIHintsMetaSchema].
+func ValidateUIHints(schema Schema) error {
+	validator := NewDefaultValidator()
+	// hints are all optional
+	validator.SkipAbsentProperties = true
+	v := &uiHintsValidator{validator: validator, meta: UIHintsMetaSchema()}
+	v.walk("", schema)
+	return errors.Join(v.errs...)
+}
+
+type uiHintsValidator struct {
+	validator *Validator
+	meta      Schema
+	errs      []error
+}
+
+func (v *uiHintsValidator) walk(location string, schema Schema) {
+	if val, ok := schema.ExtraProps[XUI]; ok {
+		data, err := ConvertToJSONCompatible(val)
+		if err != nil {
+			v.errs = append(v.errs, fmt.Errorf("%s/%s: %w", location, XUI, err))
+		} else if out := v.validator.ValidateJson(v.meta, data); !out.Valid {
+			v.errs = append(v.errs, fmt.Errorf("%s/%s: %s", location, XUI, uiHintsErrorMessage(out)))
+		}
+	}
+	walkSchemas := func(keyword string, schemas []Schema) {
+		for i, s := range schemas {
+			v.walk(location+"/"+keyword+"/"+strconv.Itoa(i), s)
+		}
+	}
+	walkSchema := func(keyword string, s *Schema) {
+		if s != nil {
+			v.walk(location+"/"+keyword, *s)
+		}
+	}
+	walkMap := func(keyword string, schemas map[string]Schema) {
+		for _, name := range slices.Sorted(maps.Keys(schemas)) {
+			v.walk(location+"/"+keyword+"/"+jsonPointerEscape(name), schemas[name])
+		}
+	}
+	for _, prop := range schema.Properties {
+		v.walk(location+"/properties/"+jsonPointerEscape(prop.Name), prop.Schema)
+	}
+	for _, prop := range schema.PatternProperties {
+		v.walk(location+"/patternProperties/"+jsonPointerEscape(prop.Name), prop.Schema)
+	}
+	if schema.AdditionalProperties != nil {
+		walkSchema("additionalProperties", schema.AdditionalProperties.Schema)
+	}
+	walkSchema("items", schema.Items)
+	walkSchemas("prefixItems", schema.PrefixItems)
+	walkSchemas("allOf", schema.AllOf)
+	walkSchemas("anyOf", schema.AnyOf)
+	walkSchemas("oneOf", schema.OneOf)
+	walkSchema("not", schema.Not)
+	walkSchema("if", schema.If)
+	walkSchema("then", schema.Then)
+	walkSchema("else", schema.Else)
+	walkMap("dependentSchemas", schema.DependentSchemas)
+	walkMap("$defs", schema.Defs)
+	walkMap("definitions", schema.Definitions)
+}
+
+// uiHintsErrorMessage returns the first leaf error of the output.
+func uiHintsErrorMessage(out OutPutError) string {
+	for len(out.Errors) > 0 {
+		out = out.Errors[0]
+	}
+	if out.InstanceLocation != "" {
+		return out.InstanceLocation + ": " + out.Message
+	}
+	return out.Message
+}
@@ -0,0 +1,61 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUIHints(t *testing.T) {
+	schema := NewObjectSchema().
+		Prop("name", String().UI(UIHints{Widget: UIWidgetInput, Order: 1, Placeholder: "my-app"})).
+		Prop("script", String().UI(UIHints{Widget: UIWidgetCode, Group: "Advanced", Options: map[string]any{"language": "bash"}})).
+		Build()
+	if err := ValidateUIHints(schema); err != nil {
+		t.Fatalf("ValidateUIHints() error = %v", err)
+	}
+
+	// preserved through marshal and unmarshal
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := Schema{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	hints, err := decoded.Properties[1].Schema.GetUIHints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &UIHints{Widget: UIWidgetCode, Group: "Advanced", Options: map[string]any{"language": "bash"}}
+	if !reflect.DeepEqual(hints, want) {
+		t.Errorf("GetUIHints() = %+v, want %+v", hints, want)
+	}
+	if !reflect.DeepEqual(decoded, schema) {
+		t.Errorf("schema changed after round trip: %s", data)
+	}
+
+	invalid := Schema{}
+	if err := json.Unmarshal([]byte(`{"type":"object","properties":{"a":{"type":"array","items":{"type":"string","x-ui":{"widget":"slider","placehodler":"x"}}}}}`), &invalid); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateUIHints(invalid); err == nil {
+		t.Errorf("ValidateUIHints() expected error on unknown widget")
+	} else if !strings.HasPrefix(err.Error(), "/properties/a/items/x-ui: ") {
+		t.Errorf("ValidateUIHints() error = %v, want located error", err)
+	}
+
+	// names are escaped in locations
+	escaped := Schema{}
+	if err := json.Unmarshal([]byte(`{"properties":{"a/b":{"x-ui":{"widget":"slider"}}},"patternProperties":{"^~x":{"x-ui":{"widget":"slider"}}},"$defs":{"c/d":{"x-ui":{"widget":"slider"}}}}`), &escaped); err != nil {
+		t.Fatal(err)
+	}
+	err = ValidateUIHints(escaped)
+	for _, location := range []string{"/properties/a~1b/x-ui: ", "/patternProperties/^~0x/x-ui: ", "/$defs/c~1d/x-ui: "} {
+		if err == nil || !strings.Contains(err.Error(), location) {
+			t.Errorf("ValidateUIHints() error = %v, want error at %s", err, location)
+		}
+	}
+}
@@ -60,6 +60,9 @@ func NewDefaultValidator() *Validator {
 type Validator struct {
 	StringFormats map[string]StringFormatValidator
 	Extensions    map[string]ExtensionValidator
+	// SkipAbsentProperties skips the schemas of properties absent from an object, leaving them to "required".
+	// By default absent properties are validated as null.
+	SkipAbsentProperties bool
 }
 
 type OutPut = OutPutError
@@ -568,7 +571,10 @@ func (v *Validator) validateObject(ctx context.Context, schema Schema, keywordLo
 		propName, propSchema := prop.Name, prop.Schema
 		// record validated keys
 		validatedKeys[propName] = struct{}{}
-		propValue := data[propName]
+		propValue, ok := data[propName]
+		if !ok && v.SkipAbsentProperties {
+			continue
+		}
 		propOutput := v.validate(ctx, propSchema, jsonPointerJoin(keywordLocation, propName), propValue, jsonPointerJoin(instanceLocation, propName))
 		if !propOutput.Valid {
 			outputs = append(outputs, propOutput)
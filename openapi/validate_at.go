@@ -0,0 +1,275 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValidateAt validates fragment against the subschema of schema which applies at
+// the instance location pointer, e.g. "/spec/containers/0".
+// It is useful for PATCH requests where only the changed subtree is available.
+// Instance locations in the output are relative to the original document.
+func ValidateAt(schema *Schema, pointer string, fragment any) OutPut {
+	return NewDefaultValidator().ValidateJsonAt(context.Background(), *schema, pointer, fragment)
+}
+
+// ValidateJsonAt validates data against the subschema of schema applicable at the instance location pointer.
+func (v *Validator) ValidateJsonAt(ctx context.Context, schema Schema, pointer string, data any) OutPut {
+	sub, keywordLocation, err := ResolveSchemaAt(schema, pointer)
+	if err != nil {
+		return OutPutError{
+			InstanceLocation: pointer,
+			KeywordLocation:  keywordLocation,
+			Message:          err.Error(),
+		}
+	}
+	return v.validate(ctx, *sub, keywordLocation, data, pointer)
+}
+
+// ResolveSchemaAt returns the subschema applicable at the instance location pointer,
+// walking properties, patternProperties, additionalProperties, items, prefixItems and allOf,
+// and following local $ref references("#/$defs/..." or "#/definitions/...").
+// Locations under anyOf or oneOf can not be resolved and return an error.
+// It also returns the keyword location of the resolved schema.
+func ResolveSchemaAt(root Schema, pointer string) (*Schema, string, error) {
+	tokens, err := splitJSONPointer(pointer)
+	if err != nil {
+		return nil, "", err
+	}
+	r := &schemaResolver{root: root}
+	current, keywordLocation := root, ""
+	for _, token := range tokens {
+		next, nextLocation, err := r.child(current, keywordLocation, token)
+		if err != nil {
+			return nil, keywordLocation, err
+		}
+		current, keywordLocation = *next, nextLocation
+	}
+	resolved, keywordLocation, err := r.deref(current, keywordLocation)
+	if err != nil {
+		return nil, keywordLocation, err
+	}
+	return &resolved, keywordLocation, nil
+}
+
+type schemaResolver struct {
+	root    Schema
+	rootRaw any
+}
+
+// maxRefDepth limits $ref chains to avoid infinite loops on cyclic references.
+const maxRefDepth = 32
+
+func (r *schemaResolver) deref(schema Schema, keywordLocation string) (Schema, string, error) {
+	for i := 0; schema.Ref != ""; i++ {
+		if i >= maxRefDepth {
+			return schema, keywordLocation, fmt.Errorf("too many $ref indirections at %s", keywordLocation)
+		}
+		target, err := r.lookupRef(schema.Ref)
+		if err != nil {
+			return schema, keywordLocation, err
+		}
+		schema, keywordLocation = target, keywordLocation+"/$ref"
+	}
+	return schema, keywordLocation, nil
+}
+
+func (r *schemaResolver) lookupRef(ref string) (Schema, error) {
+	if ref == "#" {
+		return r.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return Schema{}, fmt.Errorf("unsupported $ref %s, only local references are supported", ref)
+	}
+	tokens, err := splitJSONPointer(strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		return Schema{}, err
+	}
+	// fast path for common definitions
+	if len(tokens) == 2 {
+		switch tokens[0] {
+		case "$defs":
+			if def, ok := r.root.Defs[tokens[1]]; ok {
+				return def, nil
+			}
+		case "definitions":
+			if def, ok := r.root.Definitions[tokens[1]]; ok {
+				return def, nil
+			}
+		}
+	}
+	if r.rootRaw == nil {
+		raw, err := ConvertToJSONCompatible(r.root)
+		if err != nil {
+			return Schema{}, err
+		}
+		r.rootRaw = raw
+	}
+	current := r.rootRaw
+	for _, token := range tokens {
+		switch val := current.(type) {
+		case map[string]any:
+			next, ok := val[token]
+			if !ok {
+				return Schema{}, fmt.Errorf("$ref %s not found", ref)
+			}
+			current = next
+		case []any:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(val) {
+				return Schema{}, fmt.Errorf("$ref %s not found", ref)
+			}
+			current = val[idx]
+		default:
+			return Schema{}, fmt.Errorf("$ref %s not found", ref)
+		}
+	}
+	data, err := json.Marshal(current)
+	if err != nil {
+		return Schema{}, err
+	}
+	var target Schema
+	if err := json.Unmarshal(data, &target); err != nil {
+		return Schema{}, fmt.Errorf("$ref %s is not a schema: %w", ref, err)
+	}
+	return target, nil
+}
+
+func (r *schemaResolver) child(schema Schema, keywordLocation string, token string) (*Schema, string, error) {
+	child, location, ok, err := r.applicable(schema, keywordLocation, token)
+	if err != nil {
+		return nil, location, err
+	}
+	if !ok {
+		// any value is allowed at an unspecified location
+		return &Schema{}, location, nil
+	}
+	return child, location, nil
+}
+
+// applicable returns the subschema of schema which applies to the child token,
+// ok is false if schema does not constrain the child.
+// Subschemas of the matching property, every matching patternProperties, additionalProperties
+// if neither matched, and every allOf branch are combined by allOf.
+// Children constrained by anyOf or oneOf branches can not be resolved without the whole instance, it is an error.
+func (r *schemaResolver) applicable(schema Schema, keywordLocation string, token string) (*Schema, string, bool, error) {
+	schema, keywordLocation, err := r.deref(schema, keywordLocation)
+	if err != nil {
+		return nil, keywordLocation, false, err
+	}
+	if isArraySchema(schema) {
+		child, location, err := r.itemChild(schema, keywordLocation, token)
+		return child, location, err == nil, err
+	}
+	matches, locations := r.propertyChildren(schema, keywordLocation, token)
+	if len(matches) == 0 && schema.AdditionalProperties != nil {
+		// additionalProperties applies to children not matched by properties or patternProperties of the same schema
+		if schema.AdditionalProperties.Schema != nil {
+			matches = append(matches, *schema.AdditionalProperties.Schema)
+			locations = append(locations, keywordLocation+"/additionalProperties")
+		} else if !schema.AdditionalProperties.Allows {
+			return nil, keywordLocation, false, fmt.Errorf("property %s is not allowed", token)
+		}
+	}
+	for idx, sub := range schema.AllOf {
+		child, location, ok, err := r.applicable(sub, keywordLocation+"/allOf/"+strconv.Itoa(idx), token)
+		if err != nil {
+			return nil, location, false, err
+		}
+		if ok {
+			matches, locations = append(matches, *child), append(locations, location)
+		}
+	}
+	for _, branches := range []struct {
+		keyword string
+		schemas []Schema
+	}{{keyword: "anyOf", schemas: schema.AnyOf}, {keyword: "oneOf", schemas: schema.OneOf}} {
+		for idx, sub := range branches.schemas {
+			location := keywordLocation + "/" + branches.keyword + "/" + strconv.Itoa(idx)
+			if _, _, ok, err := r.applicable(sub, location, token); ok || err != nil {
+				return nil, location, false, fmt.Errorf("can not resolve %s through %s", token, branches.keyword)
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, keywordLocation, false, nil
+	case 1:
+		return &matches[0], locations[0], true, nil
+	default:
+		return &Schema{AllOf: matches}, keywordLocation, true, nil
+	}
+}
+
+// propertyChildren returns the subschemas of the property and all patternProperties matching the child token.
+func (r *schemaResolver) propertyChildren(schema Schema, keywordLocation string, token string) ([]Schema, []string) {
+	var matches []Schema
+	var locations []string
+	for i := range schema.Properties {
+		if schema.Properties[i].Name == token {
+			matches = append(matches, schema.Properties[i].Schema)
+			locations = append(locations, jsonPointerJoin(keywordLocation, token))
+			break
+		}
+	}
+	for i := range schema.PatternProperties {
+		pattern := schema.PatternProperties[i].Name
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString(token) {
+			matches = append(matches, schema.PatternProperties[i].Schema)
+			locations = append(locations, jsonPointerJoin(keywordLocation+"/patternProperties", pattern))
+		}
+	}
+	return matches, locations
+}
+
+func (r *schemaResolver) itemChild(schema Schema, keywordLocation string, token string) (*Schema, string, error) {
+	// "-" refers to the (nonexistent) element after the last array element
+	idx := -1
+	if token != "-" {
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 {
+			return nil, keywordLocation, fmt.Errorf("invalid array index %s", token)
+		}
+		idx = i
+	}
+	if idx >= 0 && idx < len(schema.PrefixItems) {
+		return &schema.PrefixItems[idx], keywordLocation + "/prefixItems/" + strconv.Itoa(idx), nil
+	}
+	if schema.Items != nil {
+		return schema.Items, keywordLocation + "/items", nil
+	}
+	if schema.AdditionalItems != nil && schema.AdditionalItems.Schema != nil {
+		return schema.AdditionalItems.Schema, keywordLocation + "/additionalItems", nil
+	}
+	return &Schema{}, keywordLocation, nil
+}
+
+func isArraySchema(schema Schema) bool {
+	if schema.Items != nil || len(schema.PrefixItems) > 0 {
+		return true
+	}
+	for _, typ := range schema.Type {
+		if typ == SchemaTypeArray {
+			return true
+		}
+	}
+	return false
+}
+
+func splitJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" || pointer == "/" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid json pointer %s", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = jsonPointerUnescape(token)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,191 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/go-openapi/spec"
+)
+
+func TestValidateAt(t *testing.T) {
+	container := Schema{
+		Type: spec.StringOrArray{"object"},
+		Properties: SchemaProperties{
+			{Name: "name", Schema: Schema{Type: spec.StringOrArray{"string"}, MinLength: ptrInt64(1)}},
+			{Name: "replicas", Schema: Schema{Type: spec.StringOrArray{"integer"}, Minimum: ptrFloat64(0)}},
+		},
+		Required: []string{"name"},
+	}
+	schema := &Schema{
+		Type: spec.StringOrArray{"object"},
+		Defs: map[string]Schema{"container": container},
+		Properties: SchemaProperties{
+			{Name: "spec", Schema: Schema{
+				Type: spec.StringOrArray{"object"},
+				Properties: SchemaProperties{
+					{Name: "containers", Schema: Schema{
+						Type:  spec.StringOrArray{"array"},
+						Items: &Schema{Ref: "#/$defs/container"},
+					}},
+					{Name: "labels", Schema: Schema{
+						Type:                 spec.StringOrArray{"object"},
+						AdditionalProperties: &SchemaOrBool{Schema: &Schema{Type: spec.StringOrArray{"string"}}},
+					}},
+				},
+				AdditionalProperties: &SchemaOrBool{Allows: false},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		pointer          string
+		fragment         any
+		expectValid      bool
+		instanceLocation string
+	}{
+		{
+			name:        "root",
+			pointer:     "",
+			fragment:    map[string]any{"spec": map[string]any{"containers": []any{}, "labels": map[string]any{}}},
+			expectValid: true,
+		},
+		{
+			name:        "array item via ref valid",
+			pointer:     "/spec/containers/0",
+			fragment:    map[string]any{"name": "nginx", "replicas": 1.0},
+			expectValid: true,
+		},
+		{
+			name:             "array item via ref invalid",
+			pointer:          "/spec/containers/1",
+			fragment:         map[string]any{"name": "nginx", "replicas": -1.0},
+			expectValid:      false,
+			instanceLocation: "/spec/containers/1/replicas",
+		},
+		{
+			name:             "leaf property invalid",
+			pointer:          "/spec/containers/0/name",
+			fragment:         "",
+			expectValid:      false,
+			instanceLocation: "/spec/containers/0/name",
+		},
+		{
+			name:        "additional properties schema",
+			pointer:     "/spec/labels/app~1name",
+			fragment:    "web",
+			expectValid: true,
+		},
+		{
+			name:             "additional properties not allowed",
+			pointer:          "/spec/unknown",
+			fragment:         "value",
+			expectValid:      false,
+			instanceLocation: "/spec/unknown",
+		},
+		{
+			name:             "invalid array index",
+			pointer:          "/spec/containers/abc",
+			fragment:         map[string]any{"name": "nginx"},
+			expectValid:      false,
+			instanceLocation: "/spec/containers/abc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := ValidateAt(schema, tt.pointer, tt.fragment)
+			if output.Valid != tt.expectValid {
+				t.Fatalf("expected valid=%v, got %v. Message: %s, Errors: %v", tt.expectValid, output.Valid, output.Message, output.Errors)
+			}
+			if tt.instanceLocation != "" && !hasInstanceLocation(output, tt.instanceLocation) {
+				t.Errorf("expected error at %s, got %v", tt.instanceLocation, output)
+			}
+		})
+	}
+}
+
+func hasInstanceLocation(output OutPutError, location string) bool {
+	if !output.Valid && output.InstanceLocation == location && output.Message != "" {
+		return true
+	}
+	for _, e := range output.Errors {
+		if hasInstanceLocation(e, location) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateAtComposition(t *testing.T) {
+	schema := &Schema{
+		AllOf: []Schema{
+			{Properties: SchemaProperties{{Name: "a", Schema: Schema{Type: spec.StringOrArray{"string"}}}}},
+			{Properties: SchemaProperties{{Name: "b", Schema: Schema{Type: spec.StringOrArray{"integer"}}}}},
+			{Properties: SchemaProperties{{Name: "b", Schema: Schema{Minimum: ptrFloat64(0)}}}},
+		},
+		AnyOf: []Schema{
+			{Properties: SchemaProperties{{Name: "c", Schema: Schema{Type: spec.StringOrArray{"string"}}}}},
+			{Properties: SchemaProperties{{Name: "c", Schema: Schema{Type: spec.StringOrArray{"integer"}}}}},
+		},
+	}
+	tests := []struct {
+		name        string
+		pointer     string
+		fragment    any
+		expectValid bool
+	}{
+		{name: "first branch", pointer: "/a", fragment: "value", expectValid: true},
+		{name: "declared by later branch", pointer: "/b", fragment: "notint", expectValid: false},
+		{name: "all declaring branches apply", pointer: "/b", fragment: -1.0, expectValid: false},
+		{name: "all declaring branches valid", pointer: "/b", fragment: 1.0, expectValid: true},
+		{name: "undeclared", pointer: "/d", fragment: "value", expectValid: true},
+		{name: "anyOf", pointer: "/c", fragment: "value", expectValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := ValidateAt(schema, tt.pointer, tt.fragment)
+			if output.Valid != tt.expectValid {
+				t.Fatalf("expected valid=%v, got %v. Message: %s, Errors: %v", tt.expectValid, output.Valid, output.Message, output.Errors)
+			}
+		})
+	}
+}
+
+func TestValidateAtCombinesApplicableSchemas(t *testing.T) {
+	schema := &Schema{
+		Properties: SchemaProperties{
+			{Name: "replicas", Schema: Schema{Type: spec.StringOrArray{"integer"}}},
+			{Name: "x-name", Schema: Schema{MaxLength: ptrInt64(3)}},
+		},
+		PatternProperties: SchemaProperties{
+			{Name: "^x-", Schema: Schema{Type: spec.StringOrArray{"string"}}},
+		},
+		AdditionalProperties: &SchemaOrBool{Allows: true, Schema: &Schema{Type: spec.StringOrArray{"boolean"}}},
+		AllOf: []Schema{
+			{Properties: SchemaProperties{{Name: "replicas", Schema: Schema{Minimum: ptrFloat64(1)}}}},
+		},
+	}
+	tests := []struct {
+		name        string
+		pointer     string
+		fragment    any
+		expectValid bool
+	}{
+		{name: "direct property valid", pointer: "/replicas", fragment: 2.0, expectValid: true},
+		{name: "direct property invalid", pointer: "/replicas", fragment: "two", expectValid: false},
+		{name: "allOf constrains a direct property", pointer: "/replicas", fragment: 0.0, expectValid: false},
+		{name: "property and pattern valid", pointer: "/x-name", fragment: "abc", expectValid: true},
+		{name: "property constrains a pattern property", pointer: "/x-name", fragment: "abcd", expectValid: false},
+		{name: "pattern constrains a property", pointer: "/x-name", fragment: true, expectValid: false},
+		{name: "pattern only", pointer: "/x-other", fragment: "abcd", expectValid: true},
+		{name: "additional", pointer: "/enabled", fragment: true, expectValid: true},
+		{name: "additional invalid", pointer: "/enabled", fragment: "yes", expectValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := ValidateAt(schema, tt.pointer, tt.fragment)
+			if output.Valid != tt.expectValid {
+				t.Fatalf("expected valid=%v, got %v. Message: %s, Errors: %v", tt.expectValid, output.Valid, output.Message, output.Errors)
+			}
+		})
+	}
+}
@@ -240,6 +240,17 @@ func TestValidator_ValidateJson(t *testing.T) {
 			data:        map[string]any{"name": 123},
 			expectValid: false,
 		},
+		{
+			name: "object properties absent validated as null",
+			schema: Schema{
+				Type: spec.StringOrArray{"object"},
+				Properties: SchemaProperties{
+					{Name: "name", Schema: Schema{Type: spec.StringOrArray{"string"}}},
+				},
+			},
+			data:        map[string]any{},
+			expectValid: false,
+		},
 
 		// --- Format Validation ---
 		{
@@ -450,3 +461,45 @@ func TestValidator_Extension(t *testing.T) {
 		t.Errorf("expected invalid, got valid")
 	}
 }
+
+func TestValidator_AbsentProperties(t *testing.T) {
+	schema := Schema{
+		Type: spec.StringOrArray{"object"},
+		Properties: SchemaProperties{
+			{Name: "name", Schema: Schema{Type: spec.StringOrArray{"string"}, MinLength: ptrInt64(1)}},
+			{Name: "spec", Schema: Schema{
+				Type: spec.StringOrArray{"object"},
+				Properties: SchemaProperties{
+					{Name: "replicas", Schema: Schema{Type: spec.StringOrArray{"integer"}}},
+				},
+			}},
+		},
+		Required: []string{"name"},
+	}
+	tests := []struct {
+		name        string
+		skip        bool
+		data        any
+		expectValid bool
+		errors      int
+	}{
+		{name: "absent optional", skip: true, data: map[string]any{"name": "a"}, expectValid: true},
+		{name: "absent nested optional", skip: true, data: map[string]any{"name": "a", "spec": map[string]any{}}, expectValid: true},
+		{name: "absent required reported by required only", skip: true, data: map[string]any{}, expectValid: false, errors: 1},
+		{name: "present null validated", skip: true, data: map[string]any{"name": "a", "spec": nil}, expectValid: false, errors: 1},
+		{name: "absent optional validated as null by default", data: map[string]any{"name": "a"}, expectValid: false, errors: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewDefaultValidator()
+			validator.SkipAbsentProperties = tt.skip
+			output := validator.ValidateJson(schema, tt.data)
+			if output.Valid != tt.expectValid {
+				t.Fatalf("expected valid=%v, got %v. Message: %s, Errors: %v", tt.expectValid, output.Valid, output.Message, output.Errors)
+			}
+			if !tt.expectValid && len(output.Errors) != tt.errors {
+				t.Errorf("expected %d errors, got %v", tt.errors, output.Errors)
+			}
+		})
+	}
+}
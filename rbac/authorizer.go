@@ -65,6 +65,7 @@ func HasAutorityOnce(ctx context.Context, storage store.Store, username string,
 	options := []store.ListOption{
 		store.WithSubScopes(),
 		store.WithFieldRequirementsFromSet(fields.Set{"name": username}),
+		store.WithoutSizePolicy(),
 	}
 	if err := storage.List(ctx, list, options...); err != nil {
 		return false, err
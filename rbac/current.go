@@ -21,6 +21,7 @@ func (a *API) CurrentRoles(w http.ResponseWriter, r *http.Request) {
 		options := []store.ListOption{
 			store.WithSubScopes(),
 			store.WithFieldRequirementsFromSet(map[string]string{"name": username}),
+			store.WithoutSizePolicy(),
 		}
 		if err := a.Storage.List(ctx, &list, options...); err != nil {
 			return nil, err
@@ -89,7 +89,7 @@ func (a *ScopedRbacAPI) SetUserRole(w http.ResponseWriter, r *http.Request) {
 		if LastAdminCheck && slices.Contains(exists.Roles, RoleAdmin) && setRole.Role != RoleAdmin {
 			// check must have at least one 'admin' role user
 			roleList := &store.List[UserRole]{}
-			if err := storage.List(ctx, roleList); err != nil {
+			if err := storage.List(ctx, roleList, store.WithoutSizePolicy()); err != nil {
 				return nil, err
 			}
 			adminCount := 0
@@ -152,7 +152,7 @@ func (a *ScopedRbacAPI) RemoveUserRole(w http.ResponseWriter, r *http.Request) {
 		// check the scope must have at least one 'admin' role user
 		if LastAdminCheck && slices.Contains(exists.Roles, RoleAdmin) {
 			roleList := &store.List[UserRole]{}
-			if err := storage.List(ctx, roleList); err != nil {
+			if err := storage.List(ctx, roleList, store.WithoutSizePolicy()); err != nil {
 				return nil, err
 			}
 			adminCount := 0
@@ -8,6 +8,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/ssh"
+	"xiaoshiai.cn/common/store"
 )
 
 // ErrNotProvided is returned when no authentication information is provided.
@@ -105,6 +106,9 @@ func NewAuthenticateFilter(authn Authenticator, onerr AuthenticateErrorHandleFun
 			attribute.String("user.name", info.User.Name),
 			attribute.String("user.email", info.User.Email),
 		)
-		next.ServeHTTP(w, r.WithContext(WithAuthenticate(r.Context(), *info)))
+		ctx := WithAuthenticate(r.Context(), *info)
+		// record the identity on objects written by the request
+		ctx = store.WithIdentity(ctx, info.User.Name)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -135,6 +135,15 @@ func ExtractBearerTokenFromRequest(r *http.Request) string {
 	return r.URL.Query().Get("token")
 }
 
+// ExtractDPoPTokenFromRequest returns the token of the DPoP authorization scheme(RFC 9449),
+// it is for DPoP bound tokens only and is not accepted by [BearerTokenAuthenticatorWrap].
+func ExtractDPoPTokenFromRequest(r *http.Request) string {
+	if after, ok := strings.CutPrefix(r.Header.Get("Authorization"), "DPoP "); ok {
+		return after
+	}
+	return ""
+}
+
 func BasicAuthenticatorWrap(authn BasicAuthenticator) Authenticator {
 	return AuthenticateFunc(func(w http.ResponseWriter, r *http.Request) (*AuthenticateInfo, error) {
 		username, password, ok := r.BasicAuth()
@@ -91,6 +91,7 @@ func NewRequestAuthorizationFilter(on RequestAuthorizer) Filter {
 		// already authorized by previous filter
 		decision := AuthorizationContextFromContext(r.Context())
 		if decision == DecisionAllow {
+			markAuthorized(w)
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -105,6 +106,7 @@ func NewRequestAuthorizationFilter(on RequestAuthorizer) Filter {
 			return
 		}
 		if decision == DecisionAllow {
+			markAuthorized(w)
 			// allow next filter to skip authorization
 			r = r.WithContext(WithAuthorizationContext(r.Context(), decision))
 			next.ServeHTTP(w, r)
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"xiaoshiai.cn/common/errors"
+)
+
+const (
+	APIVersionHeader = "X-API-Version"
+	APIVersionQuery  = "apiVersion"
+)
+
+// ConvertFunc converts a JSON compatible payload from one shape to another.
+// data is one of map[string]any, []any, string, float64, bool or nil.
+type ConvertFunc func(ctx context.Context, data any) (any, error)
+
+// VersionConverter converts payloads between an API version and the next newer version.
+type VersionConverter struct {
+	// Version is the old API version this converter handles.
+	Version string
+	// Upgrade converts a request payload of Version into the shape of the next newer version.
+	// nil means the request shape is unchanged.
+	Upgrade ConvertFunc
+	// Downgrade converts a response payload of the next newer version into the shape of Version.
+	// nil means the response shape is unchanged.
+	Downgrade ConvertFunc
+}
+
+// VersionConverters is a [Filter] that rewrites request bodies of old API versions into
+// the current shape before the handler and rewrites responses back into the requested version.
+//
+// Converters are ordered from the oldest version to the newest, each one converts to its successor,
+// so a request of the oldest version is upgraded through the whole chain:
+//
+//	v1 --Upgrade--> v2 --Upgrade--> current
+//	v1 <-Downgrade- v2 <-Downgrade- current
+//
+// The requested version is read from [APIVersionHeader] or [APIVersionQuery],
+// requests without a version or with the current version are not converted.
+type VersionConverters struct {
+	Current    string
+	Converters []VersionConverter
+}
+
+func NewVersionConverters(current string, converters ...VersionConverter) *VersionConverters {
+	return &VersionConverters{Current: current, Converters: converters}
+}
+
+// Convert adds version converters to the route.
+func (n Route) Convert(converters *VersionConverters) Route {
+	n.Filters = append(n.Filters, converters)
+	return n
+}
+
+// APIVersionFromRequest returns the API version requested by the client.
+func APIVersionFromRequest(r *http.Request) string {
+	if version := r.Header.Get(APIVersionHeader); version != "" {
+		return version
+	}
+	return r.URL.Query().Get(APIVersionQuery)
+}
+
+// APIVersionFromContext returns the API version requested by the client,
+// it is set by [VersionConverters] and empty if the request has not been converted.
+func APIVersionFromContext(ctx context.Context) string {
+	return GetContextValue[string](ctx, "api-version")
+}
+
+var _ Filter = &VersionConverters{}
+
+// Process implements Filter.
+func (c *VersionConverters) Process(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	version := APIVersionFromRequest(r)
+	if version == "" || version == c.Current {
+		next.ServeHTTP(w, r)
+		return
+	}
+	chain := c.chain(version)
+	if chain == nil {
+		Error(w, errors.NewBadRequest(fmt.Sprintf("unsupported api version %q", version)))
+		return
+	}
+	r = r.WithContext(SetContextValue(r.Context(), "api-version", version))
+	if err := c.convertRequest(r, chain); err != nil {
+		Error(w, err)
+		return
+	}
+	cw := &conversionResponseWriter{ResponseWriter: w}
+	next.ServeHTTP(cw, r)
+	if cw.buffer == nil {
+		return
+	}
+	body, err := c.convertResponse(r.Context(), chain, cw.buffer.Bytes())
+	if err != nil {
+		Error(w, errors.NewInternalError(fmt.Errorf("convert response to api version %s: %w", version, err)))
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(cw.status)
+	_, _ = w.Write(body)
+}
+
+// chain returns converters from version to the current version, nil if version is unknown.
+func (c *VersionConverters) chain(version string) []VersionConverter {
+	for i, converter := range c.Converters {
+		if converter.Version == version {
+			return c.Converters[i:]
+		}
+	}
+	return nil
+}
+
+func (c *VersionConverters) convertRequest(r *http.Request, chain []VersionConverter) error {
+	if r.Body == nil || r.ContentLength == 0 || !isJSONContentType(r.Header.Get("Content-Type")) {
+		return nil
+	}
+	if r.Header.Get("Content-Encoding") != "" {
+		return errors.NewUnsupported("api version conversion does not support encoded request body")
+	}
+	defer r.Body.Close()
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errors.NewBadRequest(fmt.Sprintf("read request body: %v", err))
+	}
+	var data any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return errors.NewBadRequest(fmt.Sprintf("decode request body: %v", err))
+	}
+	for _, converter := range chain {
+		if converter.Upgrade == nil {
+			continue
+		}
+		if data, err = converter.Upgrade(r.Context(), data); err != nil {
+			return errors.NewBadRequest(fmt.Sprintf("convert request from api version %s: %v", converter.Version, err))
+		}
+	}
+	converted, err := json.Marshal(data)
+	if err != nil {
+		return errors.NewInternalError(err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(converted))
+	r.ContentLength = int64(len(converted))
+	r.Header.Set("Content-Length", strconv.Itoa(len(converted)))
+	return nil
+}
+
+func (c *VersionConverters) convertResponse(ctx context.Context, chain []VersionConverter, body []byte) ([]byte, error) {
+	if len(body) == 0 {
+		return body, nil
+	}
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Downgrade == nil {
+			continue
+		}
+		var err error
+		if data, err = chain[i].Downgrade(ctx, data); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(data)
+}
+
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediatype, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediatype == "application/json" || strings.HasSuffix(mediatype, "+json")
+}
+
+// conversionResponseWriter buffers successful JSON responses so they can be converted,
+// other responses are passed through.
+type conversionResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	buffer      *bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *conversionResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader, w.status = true, code
+	contentType := w.Header().Get("Content-Type")
+	if code >= 200 && code < 300 && code != http.StatusNoContent && w.Header().Get("Content-Encoding") == "" &&
+		contentType != "" && isJSONContentType(contentType) {
+		w.buffer = bytes.NewBuffer(nil)
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *conversionResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffer != nil {
+		return w.buffer.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *conversionResponseWriter) Flush() {
+	if w.buffer != nil {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *conversionResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
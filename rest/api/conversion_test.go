@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionConverters(t *testing.T) {
+	type current struct {
+		DisplayName string `json:"displayName"`
+		Replicas    int    `json:"replicas"`
+	}
+	rename := func(from, to string) ConvertFunc {
+		return func(ctx context.Context, data any) (any, error) {
+			if m, ok := data.(map[string]any); ok {
+				if val, ok := m[from]; ok {
+					m[to] = val
+					delete(m, from)
+				}
+			}
+			return data, nil
+		}
+	}
+	converters := NewVersionConverters("v3",
+		// v1 uses "name", v2 uses "title", v3 uses "displayName"
+		VersionConverter{Version: "v1", Upgrade: rename("name", "title"), Downgrade: rename("title", "name")},
+		VersionConverter{Version: "v2", Upgrade: rename("title", "displayName"), Downgrade: rename("displayName", "title")},
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		obj := &current{}
+		if err := Body(r, obj); err != nil {
+			Error(w, err)
+			return
+		}
+		obj.Replicas++
+		Success(w, obj)
+	})
+	serve := func(version, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if version != "" {
+			req.Header.Set(APIVersionHeader, version)
+		}
+		rec := httptest.NewRecorder()
+		Filters{converters}.Process(rec, req, handler)
+		return rec
+	}
+
+	rec := serve("", `{"displayName":"foo","replicas":1}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"displayName":"foo","replicas":2}`, rec.Body.String())
+
+	rec = serve("v1", `{"name":"foo","replicas":1}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"name":"foo","replicas":2}`, rec.Body.String())
+
+	rec = serve("v2", `{"title":"foo","replicas":1}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"title":"foo","replicas":2}`, rec.Body.String())
+
+	rec = serve("v0", `{"name":"foo"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
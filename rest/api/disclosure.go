@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	liberrors "xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/log"
+)
+
+// DisclosurePolicy controls whether a caller learns that a resource exists from NotFound and Forbidden responses.
+type DisclosurePolicy string
+
+const (
+	// DisclosurePolicyAsIs responds NotFound and Forbidden as handlers and filters return them.
+	DisclosurePolicyAsIs DisclosurePolicy = "AsIs"
+	// DisclosurePolicyNotFound responds Forbidden as NotFound,
+	// so callers can not tell a resource they may not access from a missing one.
+	DisclosurePolicyNotFound DisclosurePolicy = "NotFound"
+	// DisclosurePolicyForbidden responds NotFound as Forbidden unless the request is authorized,
+	// so callers can not probe for existing resources without permission.
+	DisclosurePolicyForbidden DisclosurePolicy = "Forbidden"
+)
+
+const (
+	AuditExtraDisclosurePolicy         = "disclosure.policy"
+	AuditExtraDisclosureOriginalStatus = "disclosure.originalStatus"
+)
+
+// NewDisclosureFilter applies policy on NotFound and Forbidden errors written by [Error] in later filters and handlers.
+// The original outcome is recorded in the audit log extra metadata and logged.
+//
+// It must be installed before the authorization filter, e.g. as a global filter,
+// use [Route.Disclosure] to override the policy of a route.
+func NewDisclosureFilter(policy DisclosurePolicy) Filter {
+	return FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		if policy == "" || policy == DisclosurePolicyAsIs {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&disclosureResponseWriter{ResponseWriter: w, request: r, policy: policy}, r)
+	})
+}
+
+// Disclosure overrides the disclosure policy of the route, see [NewDisclosureFilter].
+// It applies to errors written by filters of the route and the handler,
+// errors written by global filters(e.g. a global authorization filter) follow the global policy.
+func (n Route) Disclosure(policy DisclosurePolicy) Route {
+	filter := FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		if dw := disclosureWriterFrom(w); dw != nil {
+			dw.policy = policy
+			next.ServeHTTP(w, r)
+			return
+		}
+		NewDisclosureFilter(policy).Process(w, r, next)
+	})
+	// prepend, so it applies to errors of other filters of the route
+	n.Filters = append([]Filter{filter}, n.Filters...)
+	return n
+}
+
+type disclosureResponseWriter struct {
+	http.ResponseWriter
+	request    *http.Request
+	policy     DisclosurePolicy
+	authorized bool
+}
+
+func (w *disclosureResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// disclosureWriterFrom finds the disclosure writer in the chain of wrapped writers.
+func disclosureWriterFrom(w http.ResponseWriter) *disclosureResponseWriter {
+	for {
+		switch val := w.(type) {
+		case *disclosureResponseWriter:
+			return val
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = val.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// markAuthorized records that the request passed authorization.
+func markAuthorized(w http.ResponseWriter) {
+	if dw := disclosureWriterFrom(w); dw != nil {
+		dw.authorized = true
+	}
+}
+
+// isAuthorized returns true if the request passed authorization,
+// either after the writer was installed or before, e.g. by a global filter ahead of a route override.
+func (w *disclosureResponseWriter) isAuthorized() bool {
+	return w.authorized || AuthorizationContextFromContext(w.request.Context()) == DecisionAllow
+}
+
+// applyDisclosure returns the status to respond under the disclosure policy of w.
+func applyDisclosure(w http.ResponseWriter, status *liberrors.Status) *liberrors.Status {
+	dw := disclosureWriterFrom(w)
+	if dw == nil {
+		return status
+	}
+	var converted *liberrors.Status
+	switch {
+	case dw.policy == DisclosurePolicyNotFound && status.Code == http.StatusForbidden:
+		// the resource as extracted by the attribute filter, which knows the prefix of the api
+		resource, name := "", dw.request.URL.Path
+		if attributes := AttributesFromContext(dw.request.Context()); attributes != nil && len(attributes.Resources) > 0 {
+			last := attributes.Resources[len(attributes.Resources)-1]
+			resource, name = last.Resource, last.Name
+		}
+		converted = liberrors.NewNotFound(resource, name)
+	case dw.policy == DisclosurePolicyForbidden && status.Code == http.StatusNotFound && !dw.isAuthorized():
+		converted = liberrors.NewForbidden(errors.New("access denied"))
+	default:
+		return status
+	}
+	r := dw.request
+	AddAuditLogExtra(r, AuditExtraDisclosurePolicy, string(dw.policy))
+	AddAuditLogExtra(r, AuditExtraDisclosureOriginalStatus, strconv.Itoa(int(status.Code)))
+	log.FromContext(r.Context()).V(1).Info("disclosure policy applied",
+		"policy", dw.policy, "path", r.URL.Path, "originalStatus", status.Code, "originalMessage", status.Message)
+	return converted
+}
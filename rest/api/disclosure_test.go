@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xiaoshiai.cn/common/errors"
+)
+
+func TestDisclosureFilter(t *testing.T) {
+	notfound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, errors.NewNotFound("clusters", "c1"))
+	})
+	deny := NewRequestAuthorizationFilter(RequestAuthorizerFunc(func(r *http.Request) (Decision, string, error) {
+		return DecisionDeny, "", nil
+	}))
+	allow := NewRequestAuthorizationFilter(RequestAuthorizerFunc(func(r *http.Request) (Decision, string, error) {
+		return DecisionAllow, "", nil
+	}))
+	serve := func(filters Filters, handler http.Handler) (*httptest.ResponseRecorder, *AuditLog) {
+		auditlog := &AuditLog{}
+		r := httptest.NewRequest(http.MethodGet, "/clusters/c1", nil)
+		r = r.WithContext(WithAuditLog(context.Background(), auditlog))
+		rec := httptest.NewRecorder()
+		filters.Process(rec, r, handler)
+		return rec, auditlog
+	}
+
+	rec, auditlog := serve(Filters{NewDisclosureFilter(DisclosurePolicyNotFound), deny}, notfound)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "403", auditlog.Extra[AuditExtraDisclosureOriginalStatus])
+
+	// the converted not found names the resource of the request attributes
+	r := httptest.NewRequest(http.MethodGet, "/apis/iam/v1/clusters/c1", nil)
+	r = r.WithContext(WithAuditLog(context.Background(), &AuditLog{}))
+	rec = httptest.NewRecorder()
+	Filters{NewDisclosureFilter(DisclosurePolicyNotFound), NewAttributeFilter(PrefixedAttributesExtractor("/apis/iam/v1")), deny}.Process(rec, r, notfound)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), `clusters \"c1\" not found`)
+
+	rec, _ = serve(Filters{NewDisclosureFilter(DisclosurePolicyForbidden), allow}, notfound)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "authorized callers should see not found")
+
+	rec, auditlog = serve(Filters{NewDisclosureFilter(DisclosurePolicyForbidden)}, notfound)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, "404", auditlog.Extra[AuditExtraDisclosureOriginalStatus])
+
+	rec, _ = serve(Filters{NewDisclosureFilter(DisclosurePolicyAsIs), deny}, notfound)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// route override
+	route := GET("/clusters/{name}").Disclosure(DisclosurePolicyAsIs)
+	rec, _ = serve(append(Filters{NewDisclosureFilter(DisclosurePolicyNotFound)}, append(route.Filters, deny)...), notfound)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestDisclosureRouteBehindGlobalAuthorization(t *testing.T) {
+	notfound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, errors.NewNotFound("clusters", "c1"))
+	})
+	allow := NewRequestAuthorizationFilter(RequestAuthorizerFunc(func(r *http.Request) (Decision, string, error) {
+		return DecisionAllow, "", nil
+	}))
+	route := GET("/clusters/{name}").Disclosure(DisclosurePolicyForbidden)
+
+	// global authorization filter, then the route override with a route authorization filter
+	for _, filters := range []Filters{
+		append(Filters{allow}, route.Filters...),
+		append(Filters{allow}, append(route.Filters, allow)...),
+		append(Filters{NewDisclosureFilter(DisclosurePolicyNotFound), allow}, append(route.Filters, allow)...),
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/clusters/c1", nil)
+		rec := httptest.NewRecorder()
+		filters.Process(rec, r, notfound)
+		assert.Equal(t, http.StatusNotFound, rec.Code, "authorized callers should see not found")
+	}
+}
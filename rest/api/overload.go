@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"xiaoshiai.cn/common/errors"
+)
+
+type OverloadOptions struct {
+	// MaxInflight is the maximum number of concurrently executing requests of a route class.
+	MaxInflight int `json:"maxInflight,omitempty" description:"max concurrently executing requests per route class"`
+	// MaxQueued is the maximum number of requests of a route class waiting for execution.
+	MaxQueued int `json:"maxQueued,omitempty" description:"max requests waiting for execution per route class"`
+	// MaxWait is the maximum duration a request waits in the queue.
+	MaxWait time.Duration `json:"maxWait,omitempty" description:"max duration a request waits in the queue"`
+	// RetryAfter is the duration suggested to clients in the Retry-After header when rejected.
+	RetryAfter time.Duration `json:"retryAfter,omitempty" description:"duration in Retry-After header of rejected requests"`
+}
+
+func NewDefaultOverloadOptions() *OverloadOptions {
+	return &OverloadOptions{
+		MaxInflight: 400,
+		MaxQueued:   200,
+		MaxWait:     5 * time.Second,
+		RetryAfter:  1 * time.Second,
+	}
+}
+
+// RouteClassFunc returns the class of a request, requests of the same class share the same limit.
+type RouteClassFunc func(r *http.Request) string
+
+const (
+	RouteClassReadonly = "readonly"
+	RouteClassMutating = "mutating"
+)
+
+// RouteClassByMethod separates readonly requests from mutating requests,
+// so that slow writes do not starve reads.
+func RouteClassByMethod(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RouteClassReadonly
+	default:
+		return RouteClassMutating
+	}
+}
+
+type OverloadStats struct {
+	Class    string `json:"class"`
+	Capacity int    `json:"capacity"`
+	Inflight int    `json:"inflight"`
+	Queued   int    `json:"queued"`
+	Rejected int64  `json:"rejected"`
+}
+
+// NewOverloadFilter returns a filter limits concurrently executing requests per route class.
+// Requests exceeding the limit wait in a short queue, when the queue is full or the wait times out,
+// the request is rejected with 503 and a Retry-After header.
+// classify is optional, default to [RouteClassByMethod].
+func NewOverloadFilter(options *OverloadOptions, classify RouteClassFunc) *OverloadFilter {
+	if classify == nil {
+		classify = RouteClassByMethod
+	}
+	f := &OverloadFilter{options: options, classify: classify, classes: map[string]*overloadClass{}}
+	f.initMetrics()
+	return f
+}
+
+var _ Filter = &OverloadFilter{}
+
+type OverloadFilter struct {
+	options  *OverloadOptions
+	classify RouteClassFunc
+	lock     sync.Mutex
+	classes  map[string]*overloadClass
+
+	inflightCounter metric.Int64UpDownCounter
+	queuedCounter   metric.Int64UpDownCounter
+	rejectedCounter metric.Int64Counter
+}
+
+type overloadClass struct {
+	name     string
+	slots    chan struct{}
+	queued   atomic.Int64
+	rejected atomic.Int64
+}
+
+func (f *OverloadFilter) initMetrics() {
+	meter := otel.Meter("xiaoshiai.cn/common/rest/api")
+	var err error
+	if f.inflightCounter, err = meter.Int64UpDownCounter("http.server.overload.inflight",
+		metric.WithDescription("Number of executing requests under overload protection.")); err != nil {
+		otel.Handle(err)
+	}
+	if f.queuedCounter, err = meter.Int64UpDownCounter("http.server.overload.queued",
+		metric.WithDescription("Number of requests waiting in overload protection queue.")); err != nil {
+		otel.Handle(err)
+	}
+	if f.rejectedCounter, err = meter.Int64Counter("http.server.overload.rejected",
+		metric.WithDescription("Number of requests rejected by overload protection.")); err != nil {
+		otel.Handle(err)
+	}
+}
+
+func (f *OverloadFilter) class(name string) *overloadClass {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if c, ok := f.classes[name]; ok {
+		return c
+	}
+	c := &overloadClass{name: name, slots: make(chan struct{}, max(f.options.MaxInflight, 1))}
+	f.classes[name] = c
+	return c
+}
+
+// Stats returns the saturation of each route class.
+func (f *OverloadFilter) Stats() []OverloadStats {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	stats := make([]OverloadStats, 0, len(f.classes))
+	for _, c := range f.classes {
+		stats = append(stats, OverloadStats{
+			Class:    c.name,
+			Capacity: cap(c.slots),
+			Inflight: len(c.slots),
+			Queued:   int(c.queued.Load()),
+			Rejected: c.rejected.Load(),
+		})
+	}
+	return stats
+}
+
+// Process implements Filter.
+func (f *OverloadFilter) Process(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	ctx := r.Context()
+	c := f.class(f.classify(r))
+	attrs := metric.WithAttributes(attribute.String("class", c.name))
+	if !f.acquire(ctx, c, attrs) {
+		if ctx.Err() != nil {
+			// client has gone away
+			return
+		}
+		c.rejected.Add(1)
+		f.rejectedCounter.Add(ctx, 1, attrs)
+		retryAfter := max(int(f.options.RetryAfter.Seconds()), 1)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		Error(w, errors.NewServiceUnavailable("server is overloaded, please retry later"))
+		return
+	}
+	f.inflightCounter.Add(ctx, 1, attrs)
+	defer func() {
+		<-c.slots
+		f.inflightCounter.Add(ctx, -1, attrs)
+	}()
+	next.ServeHTTP(w, r)
+}
+
+func (f *OverloadFilter) acquire(ctx context.Context, c *overloadClass, attrs metric.MeasurementOption) bool {
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if c.queued.Add(1) > int64(f.options.MaxQueued) {
+		c.queued.Add(-1)
+		return false
+	}
+	f.queuedCounter.Add(ctx, 1, attrs)
+	defer func() {
+		c.queued.Add(-1)
+		f.queuedCounter.Add(ctx, -1, attrs)
+	}()
+	timer := time.NewTimer(f.options.MaxWait)
+	defer timer.Stop()
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
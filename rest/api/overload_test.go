@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverloadFilter(t *testing.T) {
+	filter := NewOverloadFilter(&OverloadOptions{
+		MaxInflight: 1,
+		MaxQueued:   1,
+		MaxWait:     time.Second,
+		RetryAfter:  2 * time.Second,
+	}, nil)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		filter.Process(rec, httptest.NewRequest(method, "/", nil), handler)
+		return rec
+	}
+
+	results := make(chan int, 2)
+	wg := sync.WaitGroup{}
+	// first request executes
+	wg.Add(1)
+	go func() { defer wg.Done(); results <- serve(http.MethodPut).Code }()
+	<-started
+	// second request waits in the queue
+	wg.Add(1)
+	go func() { defer wg.Done(); results <- serve(http.MethodPut).Code }()
+	assert.Eventually(t, func() bool {
+		for _, s := range filter.Stats() {
+			if s.Class == RouteClassMutating {
+				return s.Queued == 1
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	// third request is rejected
+	rec := serve(http.MethodPut)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	// other classes are not affected
+	readonly := make(chan int)
+	go func() { readonly <- serve(http.MethodGet).Code }()
+
+	close(release)
+	wg.Wait()
+	close(results)
+	for code := range results {
+		assert.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, http.StatusOK, <-readonly)
+}
@@ -67,6 +67,7 @@ func Error(w http.ResponseWriter, err error) {
 	if !errors.As(err, &statuse) {
 		statuse = liberrors.NewBadRequest(err.Error())
 	}
+	statuse = applyDisclosure(w, statuse)
 	Raw(w, int(statuse.Code), WrapError(statuse))
 }
 
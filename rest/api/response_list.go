@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/meta"
+	"xiaoshiai.cn/common/store"
+)
+
+// StoreListOptions translates request [ListOptions] into store list options,
+// so servers backed by a store can pass client list options through.
+func StoreListOptions(opts ListOptions) ([]store.ListOption, error) {
+	options, err := store.ListOptionsFromMetaListOptions(opts)
+	if err != nil {
+		return nil, errors.NewBadRequest(fmt.Sprintf("invalid selector: %v", err))
+	}
+	return options, nil
+}
+
+// PageFromStoreList returns a Page from a listed store.List.
+func PageFromStoreList[T any](list *store.List[T]) Page[T] {
+	return NewPage(list.Items, list.Total, list.Page, list.Size, list.Continue)
+}
+
+// NewPage returns a Page of items.
+func NewPage[T any](items []T, total, page, size int, continueToken string) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return Page[T]{Items: items, Total: total, Page: page, Size: size, Continue: continueToken}
+}
+
+// ApplyListOptions filters, sorts and paginates items in memory the same way as store backends do.
+// It is useful for servers(e.g. webhook providers) which hold their data in memory or fetch it from
+// a backend without list options support.
+//
+// Items are matched against their JSON representation:
+// label selector matches "labels", field selector and search match fields by dotted path,
+// e.g. "status.phase=Running", "name:foo", and sort uses [store.ParseSorts].
+func ApplyListOptions[T any](items []T, opts ListOptions) (Page[T], error) {
+	labelRequirements, err := store.ParseRequirements(opts.LabelSelector)
+	if err != nil {
+		return Page[T]{}, errors.NewBadRequest(fmt.Sprintf("invalid label selector: %v", err))
+	}
+	fieldRequirements, err := store.ParseRequirements(opts.FieldSelector)
+	if err != nil {
+		return Page[T]{}, errors.NewBadRequest(fmt.Sprintf("invalid field selector: %v", err))
+	}
+	searches := meta.ParseSearch(opts.Search)
+
+	type entry struct {
+		item T
+		uns  *store.Unstructured
+	}
+	entries := make([]entry, 0, len(items))
+	for _, item := range items {
+		uns, err := toUnstructured(item)
+		if err != nil {
+			return Page[T]{}, errors.NewInternalError(err)
+		}
+		if !store.MatchLabelReqirements(uns, labelRequirements) {
+			continue
+		}
+		if !store.MatchUnstructuredFieldRequirments(uns, fieldRequirements) {
+			continue
+		}
+		if !matchSearch(uns, searches) {
+			continue
+		}
+		entries = append(entries, entry{item: item, uns: uns})
+	}
+	if sorts := store.ParseSorts(opts.Sort); len(sorts) > 0 {
+		slices.SortStableFunc(entries, func(a, b entry) int {
+			return store.CompareUnstructuredField(a.uns, b.uns, sorts)
+		})
+	}
+	matched := make([]T, 0, len(entries))
+	for _, e := range entries {
+		matched = append(matched, e.item)
+	}
+	return PageFrom(matched, opts.Page, opts.Size, nil, nil), nil
+}
+
+// matchSearch returns true if any of the search fields contains the value.
+func matchSearch(uns *store.Unstructured, searches []meta.FieldValue) bool {
+	if len(searches) == 0 {
+		return true
+	}
+	for _, search := range searches {
+		val, ok := store.GetNestedField(uns.Object, strings.Split(search.Field, ".")...)
+		if !ok {
+			continue
+		}
+		if strings.Contains(store.AnyToString(val), store.AnyToString(search.Value)) {
+			return true
+		}
+	}
+	return false
+}
+
+func toUnstructured(item any) (*store.Unstructured, error) {
+	if uns, ok := item.(*store.Unstructured); ok {
+		return uns, nil
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	uns := &store.Unstructured{}
+	if err := json.Unmarshal(data, uns); err != nil {
+		return nil, fmt.Errorf("item is not an object: %w", err)
+	}
+	return uns, nil
+}
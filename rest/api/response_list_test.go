@@ -0,0 +1,52 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyListOptions(t *testing.T) {
+	type item struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels,omitempty"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	}
+	newItem := func(name, env, phase string) item {
+		i := item{Name: name, Labels: map[string]string{"env": env}}
+		i.Status.Phase = phase
+		return i
+	}
+	items := []item{
+		newItem("foo", "prod", "Running"),
+		newItem("bar", "dev", "Running"),
+		newItem("baz", "prod", "Pending"),
+		newItem("qux", "prod", "Running"),
+	}
+	names := func(page Page[item]) []string {
+		ret := []string{}
+		for _, i := range page.Items {
+			ret = append(ret, i.Name)
+		}
+		return ret
+	}
+
+	page, err := ApplyListOptions(items, ListOptions{LabelSelector: "env=prod", Sort: "name+"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"baz", "foo", "qux"}, names(page))
+	assert.Equal(t, 3, page.Total)
+
+	page, err = ApplyListOptions(items, ListOptions{FieldSelector: "status.phase=Running", Sort: "name-", Page: 2, Size: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar"}, names(page))
+	assert.Equal(t, 3, page.Total)
+
+	page, err = ApplyListOptions(items, ListOptions{Search: "ba"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar", "baz"}, names(page))
+
+	_, err = ApplyListOptions(items, ListOptions{LabelSelector: "env in (prod"})
+	assert.Error(t, err)
+}
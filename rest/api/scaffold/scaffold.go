@@ -0,0 +1,254 @@
+// Package scaffold generates the wiring of a new service from resource definitions:
+// store setup by backend config, a REST API group per resource, authn/authz filters,
+// health and telemetry, garbage collection and controller skeletons.
+//
+// The generated service is a starting point owned by the service, regenerating does not
+// overwrite existing files. Run "go mod tidy" in the output directory to resolve dependencies.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"xiaoshiai.cn/common/store"
+)
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"lower":      strings.ToLower,
+	"lowerFirst": lowerFirst,
+}).ParseFS(templatesFS, "templates/*.tmpl"))
+
+type Options struct {
+	// Module is the Go module path of the service, e.g. "example.com/cluster-manager".
+	Module string `json:"module,omitempty"`
+	// Name is the service name, used as command name, default database name and leader election key.
+	Name string `json:"name,omitempty"`
+	// GoVersion is the go directive of the generated go.mod, default to "1.25".
+	GoVersion string     `json:"goVersion,omitempty"`
+	Resources []Resource `json:"resources,omitempty"`
+}
+
+type Resource struct {
+	// Kind is the Go type name, e.g. "Cluster".
+	// The resource name is derived from it the same way as the store does, e.g. "clusters".
+	Kind string `json:"kind,omitempty"`
+	// Scopes are the parent resources from the outermost, e.g. ["tenants"] serves the resource
+	// under "/tenants/{tenant}/clusters".
+	Scopes []string `json:"scopes,omitempty"`
+	// Fields are the fields of the spec.
+	Fields []Field `json:"fields,omitempty"`
+	// Controller generates a reconciler skeleton watching the resource.
+	Controller bool `json:"controller,omitempty"`
+}
+
+type Field struct {
+	// Name is the Go field name, the json name is the lower camel case of it.
+	Name string `json:"name,omitempty"`
+	// Type is the Go type expression, e.g. "string", "[]string", "map[string]int".
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Generate returns the generated files by path relative to the service root.
+func Generate(options *Options) (map[string][]byte, error) {
+	data, err := newTemplateData(options)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	render := func(filename, tmpl string, data any) error {
+		buf := &bytes.Buffer{}
+		if err := templates.ExecuteTemplate(buf, tmpl, data); err != nil {
+			return fmt.Errorf("render %s: %w", filename, err)
+		}
+		content := buf.Bytes()
+		if path.Ext(filename) == ".go" {
+			formatted, err := format.Source(content)
+			if err != nil {
+				return fmt.Errorf("format %s: %w", filename, err)
+			}
+			content = formatted
+		}
+		files[filename] = content
+		return nil
+	}
+	if err := render("go.mod", "go.mod.tmpl", data); err != nil {
+		return nil, err
+	}
+	if err := render(path.Join("cmd", data.Name, "main.go"), "main.go.tmpl", data); err != nil {
+		return nil, err
+	}
+	if err := render("apis/types.go", "types.go.tmpl", data); err != nil {
+		return nil, err
+	}
+	for _, filename := range []string{"options.go", "store.go", "server.go"} {
+		if err := render(path.Join("server", filename), filename+".tmpl", data); err != nil {
+			return nil, err
+		}
+	}
+	for _, resource := range data.Resources {
+		rdata := resourceTemplateData{Module: data.Module, Name: data.Name, Resource: resource}
+		if err := render(path.Join("server", resource.FileName+".go"), "api.go.tmpl", rdata); err != nil {
+			return nil, err
+		}
+		if resource.Controller {
+			if err := render(path.Join("controllers", resource.FileName+".go"), "controller.go.tmpl", rdata); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+// Write generates the service into dir.
+// It fails without writing anything if any of the files already exists.
+func Write(dir string, options *Options) error {
+	files, err := Generate(options)
+	if err != nil {
+		return err
+	}
+	for filename := range files {
+		if _, err := os.Stat(filepath.Join(dir, filename)); err == nil {
+			return fmt.Errorf("file %s already exists", filename)
+		}
+	}
+	for filename, content := range files {
+		fullpath := filepath.Join(dir, filename)
+		if err := os.MkdirAll(filepath.Dir(fullpath), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(fullpath, content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type templateData struct {
+	Module         string
+	Name           string
+	GoVersion      string
+	Resources      []resourceData
+	HasControllers bool
+}
+
+type resourceTemplateData struct {
+	Module   string
+	Name     string
+	Resource resourceData
+}
+
+type resourceData struct {
+	Kind string
+	// Resource is the plural resource name, e.g. "clusters".
+	Resource string
+	// PathVar is the path variable of the object name, e.g. "cluster".
+	PathVar    string
+	FileName   string
+	Controller bool
+	Scopes     []scopeData
+	Fields     []fieldData
+}
+
+type scopeData struct {
+	Resource string
+	PathVar  string
+}
+
+type fieldData struct {
+	Field
+	JSONName string
+}
+
+func newTemplateData(options *Options) (*templateData, error) {
+	if options.Module == "" {
+		return nil, fmt.Errorf("module is required")
+	}
+	if options.Name == "" || strings.ContainsAny(options.Name, `/\ `) {
+		return nil, fmt.Errorf("invalid service name %q", options.Name)
+	}
+	data := &templateData{Module: options.Module, Name: options.Name, GoVersion: options.GoVersion}
+	if data.GoVersion == "" {
+		data.GoVersion = "1.25"
+	}
+	resources := []string{}
+	for _, resource := range options.Resources {
+		if !token.IsIdentifier(resource.Kind) || !token.IsExported(resource.Kind) {
+			return nil, fmt.Errorf("invalid kind %q, must be an exported Go identifier", resource.Kind)
+		}
+		name := store.SimpleNameToPlural(strings.ToLower(resource.Kind))
+		if slices.Contains(resources, name) {
+			return nil, fmt.Errorf("duplicated resource %s", name)
+		}
+		resources = append(resources, name)
+
+		rdata := resourceData{
+			Kind:       resource.Kind,
+			Resource:   name,
+			PathVar:    lowerFirst(resource.Kind),
+			FileName:   strings.ToLower(resource.Kind),
+			Controller: resource.Controller,
+		}
+		for _, scope := range resource.Scopes {
+			if scope == "" || strings.ContainsAny(scope, "/{} ") {
+				return nil, fmt.Errorf("invalid scope %q of %s", scope, resource.Kind)
+			}
+			rdata.Scopes = append(rdata.Scopes, scopeData{Resource: scope, PathVar: singular(scope)})
+		}
+		for _, field := range resource.Fields {
+			if !token.IsIdentifier(field.Name) || !token.IsExported(field.Name) {
+				return nil, fmt.Errorf("invalid field %q of %s, must be an exported Go identifier", field.Name, resource.Kind)
+			}
+			if field.Type == "" {
+				return nil, fmt.Errorf("type of field %s.%s is required", resource.Kind, field.Name)
+			}
+			rdata.Fields = append(rdata.Fields, fieldData{Field: field, JSONName: lowerFirst(field.Name)})
+		}
+		data.HasControllers = data.HasControllers || resource.Controller
+		data.Resources = append(data.Resources, rdata)
+	}
+	return data, nil
+}
+
+// lowerFirst returns the lower camel case of an identifier, e.g. "DNSName" to "dnsName".
+func lowerFirst(s string) string {
+	runes := []rune(s)
+	for i := range runes {
+		next := i + 1
+		if i > 0 && next < len(runes) && !isUpper(runes[next]) {
+			break
+		}
+		if !isUpper(runes[i]) {
+			break
+		}
+		runes[i] = runes[i] + ('a' - 'A')
+	}
+	return string(runes)
+}
+
+func isUpper(r rune) bool {
+	return r >= 'A' && r <= 'Z'
+}
+
+// singular is the reverse of [store.SimpleNameToPlural].
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "s"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
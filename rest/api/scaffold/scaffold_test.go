@@ -0,0 +1,155 @@
+package scaffold
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testOptions() *Options {
+	return &Options{
+		Module: "example.com/demo",
+		Name:   "demo",
+		Resources: []Resource{
+			{Kind: "Tenant"},
+			{
+				Kind:       "Cluster",
+				Scopes:     []string{"tenants"},
+				Fields:     []Field{{Name: "APIServer", Type: "string"}, {Name: "Replicas", Type: "int"}},
+				Controller: true,
+			},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	files, err := Generate(testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{
+		"apis/types.go",
+		"cmd/demo/main.go",
+		"controllers/cluster.go",
+		"go.mod",
+		"server/cluster.go",
+		"server/options.go",
+		"server/server.go",
+		"server/store.go",
+		"server/tenant.go",
+	}
+	if !slices.Equal(names, want) {
+		t.Fatalf("Generate() files = %v, want %v", names, want)
+	}
+	contains := map[string][]string{
+		"apis/types.go":          {"APIServer string `json:\"apiServer,omitempty\"`"},
+		"server/cluster.go":      {`NewGroup("/tenants/{tenant}/clusters")`, `api.GET("/{cluster}")`},
+		"server/server.go":       {"NewClusterAPI(storage).Group()", "controllers.NewClusterController(storage)", `"clusters",`},
+		"controllers/cluster.go": {`"demo/cluster"`},
+	}
+	for name, substrs := range contains {
+		for _, substr := range substrs {
+			if !strings.Contains(string(files[name]), substr) {
+				t.Errorf("%s does not contain %s:\n%s", name, substr, files[name])
+			}
+		}
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	tests := []func(o *Options){
+		func(o *Options) { o.Module = "" },
+		func(o *Options) { o.Resources[0].Kind = "tenant" },
+		func(o *Options) { o.Resources[1].Kind = "Tenant" },
+		func(o *Options) { o.Resources[1].Fields[0].Type = "" },
+		func(o *Options) { o.Resources[1].Fields[0].Type = "map[string" },
+	}
+	for i, modify := range tests {
+		options := testOptions()
+		modify(options)
+		if _, err := Generate(options); err == nil {
+			t.Errorf("case %d: Generate() expected error", i)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	if err := Write(dir, testOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "server", "server.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(dir, testOptions()); err == nil {
+		t.Errorf("Write() should not overwrite existing files")
+	}
+}
+
+// TestGeneratedBuilds generates a service into a temporary module using this tree, then vets and builds it.
+func TestGeneratedBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated service")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := Write(dir, testOptions()); err != nil {
+		t.Fatal(err)
+	}
+	// resolve dependencies by the requirements of this module instead of "go mod tidy", which needs network
+	rootmod, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, requires, ok := strings.Cut(string(rootmod), "\nrequire")
+	if !ok {
+		t.Fatal("no requirements in go.mod")
+	}
+	gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gomod = fmt.Appendf(gomod, "\nrequire%s\nrequire xiaoshiai.cn/common v0.0.0\n\nreplace xiaoshiai.cn/common => %s\n", requires, root)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), gomod, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gosum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), gosum, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"vet", "./..."}, {"build", "./..."}} {
+		cmd := exec.Command(gobin, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+}
+
+func TestLowerFirst(t *testing.T) {
+	for in, want := range map[string]string{"Cluster": "cluster", "APIServer": "apiServer", "ID": "id", "DNSName": "dnsName"} {
+		if got := lowerFirst(in); got != want {
+			t.Errorf("lowerFirst(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
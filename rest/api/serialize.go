@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/log"
+	"xiaoshiai.cn/common/rand"
+	"xiaoshiai.cn/common/store"
+)
+
+// Locker locks a key, Lock blocks until the lock is acquired or ctx is done.
+type Locker interface {
+	Lock(ctx context.Context, key string) (unlock func(), err error)
+}
+
+// ResourceKeyFunc returns the key of the object a request writes to, empty means the request is not serialized.
+type ResourceKeyFunc func(r *http.Request) string
+
+// ResourceKeyFromRequest returns the key of the object from the request attributes,
+// scopes, resource and name joined by "/", e.g. "tenants/t1/clusters/c1".
+// It returns empty for requests not targeting a single object, e.g. list or create.
+func ResourceKeyFromRequest(r *http.Request) string {
+	var resources []AttrbuteResource
+	if attributes := AttributesFromContext(r.Context()); attributes != nil {
+		resources = attributes.Resources
+	} else {
+		_, resources = DefaultRestAttributeExtractor(r.Method, r.URL.Path)
+	}
+	if len(resources) == 0 || resources[len(resources)-1].Name == "" {
+		return ""
+	}
+	parts := make([]string, 0, len(resources)*2)
+	for _, resource := range resources {
+		parts = append(parts, resource.Resource, resource.Name)
+	}
+	return strings.Join(parts, "/")
+}
+
+type SerializeOptions struct {
+	// MaxWait is the maximum duration a write waits for the lock, 0 means wait until the request is done.
+	MaxWait time.Duration `json:"maxWait,omitempty" description:"max duration a write waits for the object lock"`
+}
+
+// NewSerializeFilter returns a filter serializes write requests(non GET/HEAD/OPTIONS) to the same object,
+// so concurrent writes to an object are handled one by one instead of conflicting with each other.
+// locker is default to an in-process [KeyedMutex], use [StoreLocker] for multi-replica deployments.
+// keyfunc is default to [ResourceKeyFromRequest].
+func NewSerializeFilter(options *SerializeOptions, locker Locker, keyfunc ResourceKeyFunc) Filter {
+	if locker == nil {
+		locker = NewKeyedMutex()
+	}
+	if keyfunc == nil {
+		keyfunc = ResourceKeyFromRequest
+	}
+	if options == nil {
+		options = &SerializeOptions{}
+	}
+	return FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := keyfunc(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		lockctx := ctx
+		if options.MaxWait > 0 {
+			var cancel context.CancelFunc
+			lockctx, cancel = context.WithTimeout(ctx, options.MaxWait)
+			defer cancel()
+		}
+		unlock, err := locker.Lock(lockctx, key)
+		if err != nil {
+			if ctx.Err() != nil {
+				// client has gone away
+				return
+			}
+			if lockctx.Err() != nil {
+				Error(w, errors.NewCustomError(http.StatusConflict, errors.StatusReasonConflict, "too many concurrent writes to "+key))
+				return
+			}
+			Error(w, errors.NewServiceUnavailable("lock "+key+": "+err.Error()))
+			return
+		}
+		defer unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Serialize serializes write requests to the same object of the route, see [NewSerializeFilter].
+func (n Route) Serialize(locker Locker) Route {
+	n.Filters = append(n.Filters, NewSerializeFilter(nil, locker, nil))
+	return n
+}
+
+// NewKeyedMutex returns an in-process Locker.
+func NewKeyedMutex() *KeyedMutex {
+	return &KeyedMutex{locks: map[string]*keyedMutexEntry{}}
+}
+
+var _ Locker = &KeyedMutex{}
+
+// KeyedMutex is a mutex per key, entries are removed once no one holds or waits for them.
+type KeyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedMutexEntry
+}
+
+type keyedMutexEntry struct {
+	ch   chan struct{}
+	refs int
+}
+
+func (m *KeyedMutex) Lock(ctx context.Context, key string) (func(), error) {
+	m.mu.Lock()
+	entry, ok := m.locks[key]
+	if !ok {
+		entry = &keyedMutexEntry{ch: make(chan struct{}, 1)}
+		m.locks[key] = entry
+	}
+	entry.refs++
+	m.mu.Unlock()
+
+	select {
+	case entry.ch <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				<-entry.ch
+				m.release(key, entry)
+			})
+		}, nil
+	case <-ctx.Done():
+		m.release(key, entry)
+		return nil, ctx.Err()
+	}
+}
+
+func (m *KeyedMutex) release(key string, entry *keyedMutexEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry.refs--
+	if entry.refs == 0 {
+		delete(m.locks, key)
+	}
+}
+
+type StoreLockerOptions struct {
+	// Resource is the resource of lock objects in the store.
+	Resource string `json:"resource,omitempty" description:"resource of lock objects in the store"`
+	// TTL is the duration a lock is valid without renewal, held locks are renewed every TTL/3.
+	// locks of crashed replicas are taken over after TTL.
+	TTL time.Duration `json:"ttl,omitempty" description:"duration a lock is valid without renewal"`
+	// RetryInterval is the interval to retry acquiring a held lock.
+	RetryInterval time.Duration `json:"retryInterval,omitempty" description:"interval to retry acquiring a held lock"`
+}
+
+func NewDefaultStoreLockerOptions() *StoreLockerOptions {
+	return &StoreLockerOptions{
+		Resource:      "locks",
+		TTL:           30 * time.Second,
+		RetryInterval: 100 * time.Millisecond,
+	}
+}
+
+// NewStoreLocker returns a Locker backed by the store, for serializing writes across replicas.
+// Each acquisition creates a lock object, which works on any backend supporting
+// unique ids and enforcing field requirements on delete and patch atomically with the write,
+// as the etcd, etcdcache, mongo and sql stores do.
+func NewStoreLocker(s store.Store, options *StoreLockerOptions) *StoreLocker {
+	return &StoreLocker{Store: s, Options: options}
+}
+
+var _ Locker = &StoreLocker{}
+
+type StoreLocker struct {
+	Store   store.Store
+	Options *StoreLockerOptions
+}
+
+func (l *StoreLocker) Lock(ctx context.Context, key string) (func(), error) {
+	sum := sha256.Sum256([]byte(key))
+	id := hex.EncodeToString(sum[:16])
+	holder := rand.RandomAlphaNumeric(16)
+	for {
+		ok, err := l.tryLock(ctx, id, key, holder)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(l.Options.RetryInterval):
+		}
+	}
+	renewctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	released := &atomic.Bool{}
+	go l.renew(renewctx, id, key, holder, released)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			released.Store(true)
+			// the request context may be done already, release on a fresh one,
+			// and stop renewing only after the lock object is deleted
+			releasectx, releasecancel := context.WithTimeout(context.WithoutCancel(ctx), l.Options.TTL)
+			defer releasecancel()
+			defer cancel()
+			lock := l.lockObject(id, "", "")
+			if err := l.Store.Delete(releasectx, lock, store.WithDeleteFieldRequirements(store.RequirementEqual("holder", holder))); err != nil && !errors.IsNotFound(err) {
+				log.FromContext(ctx).Error(err, "release lock", "key", key)
+			}
+		})
+	}, nil
+}
+
+func (l *StoreLocker) tryLock(ctx context.Context, id, key, holder string) (bool, error) {
+	lock := l.lockObject(id, key, holder)
+	lock.Object["expire"] = time.Now().Add(l.Options.TTL).UnixMilli()
+	err := l.Store.Create(ctx, lock)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.IsAlreadyExists(err) {
+		return false, err
+	}
+	current := l.lockObject(id, "", "")
+	if err := l.Store.Get(ctx, id, current); err != nil {
+		if errors.IsNotFound(err) {
+			// released just now
+			return false, nil
+		}
+		return false, err
+	}
+	if store.GetNestedInt64(current.Object, "expire") > time.Now().UnixMilli() {
+		return false, nil
+	}
+	// expired, the holder has gone. take over by deleting the exact lock we have seen,
+	// another replica may take over at the same time, only one of the deletions matches.
+	// a renewal in between changes expire, the renewed lock is not deleted.
+	seen := store.WithDeleteFieldRequirements(
+		store.RequirementEqual("holder", current.GetNestedString("holder")),
+		store.RequirementEqual("expire", current.Object["expire"]),
+	)
+	if err := l.Store.Delete(ctx, current, seen); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return false, nil
+}
+
+// renew extends the lock until ctx is done.
+// It stops once the lock is lost, e.g. taken over after a renewal failed for longer than TTL,
+// the write holding it is no longer serialized and it is logged as an error.
+func (l *StoreLocker) renew(ctx context.Context, id, key, holder string, released *atomic.Bool) {
+	ticker := time.NewTicker(l.Options.TTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			patch := store.MapMergePatch{"expire": time.Now().Add(l.Options.TTL).UnixMilli()}
+			lock := l.lockObject(id, "", "")
+			err := l.Store.Patch(ctx, lock, patch, store.WithPatchFieldRequirements(store.RequirementEqual("holder", holder)))
+			if err == nil || ctx.Err() != nil || released.Load() {
+				continue
+			}
+			if errors.IsNotFound(err) {
+				log.FromContext(ctx).Error(err, "lock lost", "key", key)
+				return
+			}
+			log.FromContext(ctx).Error(err, "renew lock", "key", key)
+		}
+	}
+}
+
+func (l *StoreLocker) lockObject(id, key, holder string) *store.Unstructured {
+	lock := &store.Unstructured{Object: map[string]any{}}
+	lock.SetResource(l.Options.Resource)
+	lock.SetID(id)
+	if key != "" {
+		lock.Object["key"] = key
+	}
+	if holder != "" {
+		lock.Object["holder"] = holder
+	}
+	return lock
+}
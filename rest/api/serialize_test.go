@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/store"
+)
+
+func TestResourceKeyFromRequest(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{method: http.MethodPut, path: "/tenants/t1/clusters/c1", want: "tenants/t1/clusters/c1"},
+		{method: http.MethodPost, path: "/tenants/t1/clusters", want: ""},
+		{method: http.MethodPost, path: "/tenants/t1/clusters/c1:restart", want: "tenants/t1/clusters/c1"},
+	}
+	for _, tt := range tests {
+		got := ResourceKeyFromRequest(httptest.NewRequest(tt.method, tt.path, nil))
+		assert.Equal(t, tt.want, got, tt.path)
+	}
+}
+
+func TestSerializeFilter(t *testing.T) {
+	filter := NewSerializeFilter(nil, nil, nil)
+
+	var running, maxRunning atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			max := maxRunning.Load()
+			if n <= max || maxRunning.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	})
+	serve := func(method, path string) {
+		filter.Process(httptest.NewRecorder(), httptest.NewRequest(method, path, nil), handler)
+	}
+
+	wg := sync.WaitGroup{}
+	for range 5 {
+		wg.Add(1)
+		go func() { defer wg.Done(); serve(http.MethodPut, "/clusters/c1") }()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), maxRunning.Load(), "writes to the same object should be serialized")
+
+	maxRunning.Store(0)
+	for _, path := range []string{"/clusters/c1", "/clusters/c2"} {
+		wg.Add(1)
+		go func() { defer wg.Done(); serve(http.MethodPut, path) }()
+	}
+	for range 2 {
+		wg.Add(1)
+		go func() { defer wg.Done(); serve(http.MethodGet, "/clusters/c1") }()
+	}
+	wg.Wait()
+	assert.Greater(t, maxRunning.Load(), int32(1), "reads and writes to different objects should not be serialized")
+}
+
+func TestSerializeFilterMaxWait(t *testing.T) {
+	locker := NewKeyedMutex()
+	filter := NewSerializeFilter(&SerializeOptions{MaxWait: 10 * time.Millisecond}, locker, nil)
+
+	unlock, err := locker.Lock(context.Background(), "clusters/c1")
+	assert.NoError(t, err)
+	defer unlock()
+
+	rec := httptest.NewRecorder()
+	filter.Process(rec, httptest.NewRequest(http.MethodPut, "/clusters/c1", nil), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	}))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	unlock()
+	assert.Empty(t, locker.locks, "released entries should be removed")
+}
+
+// lockStore is a store of unstructured lock objects, it fails on done contexts as real backends do.
+type lockStore struct {
+	store.Store
+	mu      sync.Mutex
+	objects map[string]map[string]any
+	// afterGet is called after each get, to change objects between a read and a write.
+	afterGet func()
+}
+
+func (s *lockStore) Create(ctx context.Context, obj store.Object, opts ...store.CreateOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[obj.GetID()]; ok {
+		return errors.NewAlreadyExists(obj.GetResource(), obj.GetID())
+	}
+	s.objects[obj.GetID()] = maps.Clone(obj.(*store.Unstructured).Object)
+	return nil
+}
+
+func (s *lockStore) Get(ctx context.Context, id string, obj store.Object, opts ...store.GetOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.objects[id]
+	if !ok {
+		return errors.NewNotFound(obj.GetResource(), id)
+	}
+	obj.(*store.Unstructured).Object = maps.Clone(current)
+	if s.afterGet != nil {
+		// called outside the lock
+		s.mu.Unlock()
+		s.afterGet()
+		s.mu.Lock()
+	}
+	return nil
+}
+
+func (s *lockStore) Delete(ctx context.Context, obj store.Object, opts ...store.DeleteOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	options := store.DeleteOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.objects[obj.GetID()]
+	if !ok || !store.MatchUnstructuredFieldRequirments(&store.Unstructured{Object: current}, options.FieldRequirements) {
+		return errors.NewNotFound(obj.GetResource(), obj.GetID())
+	}
+	delete(s.objects, obj.GetID())
+	return nil
+}
+
+func (s *lockStore) Patch(ctx context.Context, obj store.Object, patch store.Patch, opts ...store.PatchOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	options := store.PatchOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.objects[obj.GetID()]
+	if !ok || !store.MatchUnstructuredFieldRequirments(&store.Unstructured{Object: current}, options.FieldRequirements) {
+		return errors.NewNotFound(obj.GetResource(), obj.GetID())
+	}
+	return json.Unmarshal(data, &current)
+}
+
+func TestStoreLocker(t *testing.T) {
+	fake := &lockStore{objects: map[string]map[string]any{}}
+	locker := NewStoreLocker(fake, NewDefaultStoreLockerOptions())
+
+	// the lock of a request is released after the request context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	unlock, err := locker.Lock(ctx, "clusters/c1")
+	assert.NoError(t, err)
+	cancel()
+	unlock()
+	assert.Empty(t, fake.objects, "lock object should be deleted on unlock")
+
+	lockctx, lockcancel := context.WithTimeout(context.Background(), time.Second)
+	defer lockcancel()
+	start := time.Now()
+	unlock, err = locker.Lock(lockctx, "clusters/c1")
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "released lock should be acquired immediately")
+
+	// held locks are not acquired by others
+	waitctx, waitcancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer waitcancel()
+	_, err = locker.Lock(waitctx, "clusters/c1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	unlock()
+}
+
+func TestStoreLockerTakeover(t *testing.T) {
+	fake := &lockStore{objects: map[string]map[string]any{}}
+	locker := NewStoreLocker(fake, NewDefaultStoreLockerOptions())
+	ctx := context.Background()
+	expired := time.Now().Add(-time.Second).UnixMilli()
+
+	// an expired lock is taken over
+	fake.objects["l1"] = map[string]any{"id": "l1", "holder": "h1", "expire": expired}
+	ok, err := locker.tryLock(ctx, "l1", "key", "h2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NotContains(t, fake.objects, "l1", "expired lock should be deleted")
+
+	// an expired lock renewed by its holder after it was read is kept
+	fake.objects["l1"] = map[string]any{"id": "l1", "holder": "h1", "expire": expired}
+	fake.afterGet = func() {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		fake.objects["l1"]["expire"] = time.Now().Add(time.Minute).UnixMilli()
+	}
+	ok, err = locker.tryLock(ctx, "l1", "key", "h2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	if assert.Contains(t, fake.objects, "l1", "renewed lock should not be deleted") {
+		assert.Equal(t, "h1", fake.objects["l1"]["holder"])
+	}
+}
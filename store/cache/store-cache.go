@@ -117,6 +117,9 @@ func (g *CacheStore) List(ctx context.Context, list store.ObjectList, opts ...st
 	for _, opt := range opts {
 		opt(options)
 	}
+	if err := store.ApplyListPolicy(resource, options); err != nil {
+		return err
+	}
 	if list == nil {
 		return errors.NewBadRequest("object list is nil")
 	}
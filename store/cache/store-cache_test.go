@@ -2,6 +2,8 @@ package cache
 
 import (
 	"context"
+	"net/http"
+	"strconv"
 	"testing"
 
 	"k8s.io/apiserver/pkg/storage/etcd3/testserver"
@@ -78,3 +80,49 @@ func TestCacheStore_Create(t *testing.T) {
 		t.Fatalf("failed to get object: %v", err)
 	}
 }
+
+type policyObject struct {
+	store.ObjectMeta `json:",inline"`
+	Value            string `json:"value,omitempty"`
+}
+
+// TestListPolicyAcrossBackends lists with the same policy from etcd and the cache of it,
+// both must return the same pages.
+func TestListPolicyAcrossBackends(t *testing.T) {
+	ctx, etcdStore, cleanup := SetupEtcdTestEtcdStore(t)
+	defer cleanup()
+
+	store.GlobalListPolicies.Register("policyobjects", store.ListPolicy{DefaultSort: "value", DefaultSize: 2, MaxSize: 3})
+	t.Cleanup(func() { store.GlobalListPolicies.Register("policyobjects", store.ListPolicy{}) })
+
+	// names are not in the order of values
+	for i, value := range []string{"c", "a", "d", "b"} {
+		name := "obj-" + strconv.Itoa(i)
+		obj := &policyObject{ObjectMeta: store.ObjectMeta{ID: name, Name: name, Resource: "policyobjects"}, Value: value}
+		if err := etcdStore.Create(ctx, obj); err != nil {
+			t.Fatalf("failed to create object: %v", err)
+		}
+	}
+	backends := map[string]store.Store{"etcd": etcdStore, "cache": NewCacheStore(etcdStore)}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			for page, want := range map[int]string{1: "ab", 2: "cd"} {
+				list := &store.List[policyObject]{Resource: "policyobjects"}
+				if err := backend.List(ctx, list, store.WithPageSize(page, 0)); err != nil {
+					t.Fatalf("failed to list objects: %v", err)
+				}
+				got := ""
+				for _, item := range list.Items {
+					got += item.Value
+				}
+				if got != want || list.Size != 2 {
+					t.Errorf("page %d = %q of size %d, want %q of size 2", page, got, list.Size, want)
+				}
+			}
+			list := &store.List[policyObject]{Resource: "policyobjects"}
+			if err := backend.List(ctx, list, store.WithPageSize(1, 4)); !errors.IsCode(err, http.StatusBadRequest) {
+				t.Errorf("list exceeds the maximum size error = %v, want bad request", err)
+			}
+		})
+	}
+}
@@ -177,6 +177,7 @@ func (e *EtcdStore) Create(ctx context.Context, obj store.Object, opts ...store.
 	}
 	obj.SetUID(uuid.New().String())
 	obj.SetCreationTimestamp(meta.Now())
+	store.SetCreatedBy(ctx, obj)
 	obj.SetScopes(e.scopes)
 	obj.SetResource(resource)
 
@@ -255,14 +256,57 @@ func (e *EtcdStore) Delete(ctx context.Context, obj store.Object, opts ...store.
 			// 			fmt.Errorf("resourceVersion %d does not match", rev))
 			// 	}
 			// }
+			if err := checkRequirements(current, deleteoptions.LabelRequirements, deleteoptions.FieldRequirements); err != nil {
+				return nil, err
+			}
 			current.SetDeletionTimestamp(obj.GetDeletionTimestamp())
 			current.SetFinalizers(obj.GetFinalizers())
 			return current, nil
 		}
 		return e.core.tryUpdate(ctx, e.scopes, obj, updatefunc, tryUpdateOptions{UseUnstructured: true})
 	}
-	// return e.core.directDelete(ctx, e.scopes, obj, deleteoptions.ResourceVersion)
-	return e.core.directDelete(ctx, e.scopes, obj, 0)
+	if len(deleteoptions.LabelRequirements) == 0 && len(deleteoptions.FieldRequirements) == 0 {
+		// return e.core.directDelete(ctx, e.scopes, obj, deleteoptions.ResourceVersion)
+		return e.core.directDelete(ctx, e.scopes, obj, 0)
+	}
+	// delete the revision checked against the requirements, retry if it is changed in between
+	resource, err := store.GetResource(obj)
+	if err != nil {
+		return err
+	}
+	key := e.core.getkey(e.scopes, resource, obj.GetID())
+	for range 5 {
+		current := &store.Unstructured{}
+		current.SetResource(resource)
+		current.SetID(obj.GetID())
+		if _, err := e.core.getCurrent(ctx, key, current, 0); err != nil {
+			return err
+		}
+		if err := checkRequirements(current, deleteoptions.LabelRequirements, deleteoptions.FieldRequirements); err != nil {
+			return err
+		}
+		err := e.core.directDelete(ctx, e.scopes, obj, current.GetResourceVersion())
+		if !errors.IsConflict(err) {
+			return err
+		}
+	}
+	return errors.NewConflict(resource, obj.GetID(), fmt.Errorf("max retries reached"))
+}
+
+// checkRequirements returns not found if current does not match the requirements,
+// as other backends filtering by requirements do.
+func checkRequirements(current store.Object, labels, fields store.Requirements) error {
+	if len(labels) == 0 && len(fields) == 0 {
+		return nil
+	}
+	uns, err := store.ToUnstructured(current)
+	if err != nil {
+		return errors.NewInternalError(err)
+	}
+	if !store.MatchLabelReqirements(current, labels) || !store.MatchUnstructuredFieldRequirments(uns, fields) {
+		return errors.NewNotFound(current.GetResource(), current.GetID())
+	}
+	return nil
 }
 
 // Get implements Store.
@@ -295,6 +339,9 @@ func (e *EtcdStore) List(ctx context.Context, list store.ObjectList, opts ...sto
 	for _, opt := range opts {
 		opt(options)
 	}
+	if err := store.ApplyListPolicy(resource, options); err != nil {
+		return err
+	}
 	if err := e.core.validateObjectList(list); err != nil {
 		return err
 	}
@@ -315,7 +362,13 @@ func (e *EtcdStore) List(ctx context.Context, list store.ObjectList, opts ...sto
 	limit := options.Size
 	skip := 0
 	if options.Page-1 > 0 && limit > 0 {
-		skip = options.Page * limit
+		skip = (options.Page - 1) * limit
+	}
+	// keys are in name order, other orders require all objects to be sorted before paging
+	sorts := store.ParseSorts(options.Sort)
+	sortInMemory := !isNameOrder(sorts)
+	if sortInMemory {
+		limit, skip = 0, 0
 	}
 	// clean existing items
 	v.SetZero()
@@ -373,15 +426,68 @@ func (e *EtcdStore) List(ctx context.Context, list store.ObjectList, opts ...sto
 			break
 		}
 	}
+	if sortInMemory {
+		if err := sortAndPage(v, sorts, options.Page, options.Size); err != nil {
+			return err
+		}
+	}
 	if v.IsNil() {
 		// Ensure that we never return a nil Items pointer in the result for consistency.
 		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
 	}
+	list.SetPage(options.Page)
+	list.SetSize(options.Size)
 	list.SetResourceVersion(ptr.Deref(withRev, 0))
 	list.SetScopes(e.scopes)
 	return nil
 }
 
+// isNameOrder returns true if sorts are satisfied by the key order of etcd.
+func isNameOrder(sorts []meta.SortField) bool {
+	if len(sorts) == 0 {
+		return true
+	}
+	if len(sorts) > 1 || sorts[0].Direction == meta.SortDirectionDesc {
+		return false
+	}
+	return sorts[0].Field == "name" || sorts[0].Field == "metadata.name"
+}
+
+// sortAndPage sorts the items of v and keeps the page of them.
+func sortAndPage(v reflect.Value, sorts []meta.SortField, page, size int) error {
+	for i := range sorts {
+		// ascending by default, the same as other backends
+		if sorts[i].Direction == meta.SortDirectionUnknown {
+			sorts[i].Direction = meta.SortDirectionAsc
+		}
+	}
+	items := make([]*store.Unstructured, v.Len())
+	for i := range v.Len() {
+		uns, err := store.ToUnstructured(v.Index(i).Addr().Interface().(store.Object))
+		if err != nil {
+			return errors.NewInternalError(err)
+		}
+		items[i] = uns
+	}
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	slices.SortStableFunc(indexes, func(a, b int) int {
+		return store.CompareUnstructuredField(items[a], items[b], sorts)
+	})
+	if size > 0 {
+		start := min((max(page, 1)-1)*size, len(indexes))
+		indexes = indexes[start:min(start+size, len(indexes))]
+	}
+	sorted := reflect.MakeSlice(v.Type(), 0, len(indexes))
+	for _, i := range indexes {
+		sorted = reflect.Append(sorted, v.Index(i))
+	}
+	v.Set(sorted)
+	return nil
+}
+
 // Patch implements Store.
 func (e *EtcdStore) Patch(ctx context.Context, obj store.Object, patch store.Patch, opts ...store.PatchOption) error {
 	options := &store.PatchOptions{}
@@ -389,11 +495,15 @@ func (e *EtcdStore) Patch(ctx context.Context, obj store.Object, patch store.Pat
 		opt(options)
 	}
 	updatefunc := func(current store.Object) (store.Object, error) {
+		if err := checkRequirements(current, options.LabelRequirements, options.FieldRequirements); err != nil {
+			return nil, err
+		}
 		// backup status field
 		status, hashStatus, err := GetObjectField(obj, "Status")
 		if err != nil {
 			return nil, errors.NewBadRequest(err.Error())
 		}
+		createdBy, updatedBy := current.GetCreatedBy(), current.GetUpdatedBy()
 		// apply patch
 		if err := store.ApplyPatch(current, obj, patch); err != nil {
 			return nil, err
@@ -404,6 +514,8 @@ func (e *EtcdStore) Patch(ctx context.Context, obj store.Object, patch store.Pat
 				return nil, err
 			}
 		}
+		current.SetCreatedBy(createdBy)
+		store.SetUpdatedBy(ctx, current, updatedBy)
 		return current, nil
 	}
 	return e.core.tryUpdate(ctx, e.scopes, obj, updatefunc, tryUpdateOptions{UseUnstructured: true})
@@ -436,6 +548,8 @@ func (e *EtcdStore) Update(ctx context.Context, obj store.Object, opts ...store.
 		if err := CopyField(obj, current, "Status"); err != nil {
 			return nil, err
 		}
+		obj.SetCreatedBy(current.GetCreatedBy())
+		store.SetUpdatedBy(ctx, obj, current.GetUpdatedBy())
 		return obj, nil
 	}
 	return e.core.tryUpdate(ctx, e.scopes, obj, updatefunc, tryUpdateOptions{TTL: int64(options.TTL.Seconds())})
@@ -113,3 +113,103 @@ func TestCacheStore_Get(t *testing.T) {
 		t.Fatalf("expected not found, got %v", exists)
 	}
 }
+
+type identityObject struct {
+	store.ObjectMeta `json:",inline"`
+}
+
+func TestEtcdStoreIdentity(t *testing.T) {
+	ctx := context.Background()
+	etcdStore := SetupEtcdTestEtcdStore(t)
+
+	get := func() *identityObject {
+		t.Helper()
+		exists := &identityObject{ObjectMeta: store.ObjectMeta{Resource: "test"}}
+		if err := etcdStore.Get(ctx, "test", exists); err != nil {
+			t.Fatalf("failed to get object: %v", err)
+		}
+		return exists
+	}
+
+	// values sent by clients are ignored
+	obj := &identityObject{ObjectMeta: store.ObjectMeta{ID: "test", Name: "test", Resource: "test", CreatedBy: "mallory", UpdatedBy: "mallory"}}
+	if err := etcdStore.Create(store.WithIdentity(ctx, "alice"), obj); err != nil {
+		t.Fatalf("failed to create object: %v", err)
+	}
+	if exists := get(); exists.CreatedBy != "alice" || exists.UpdatedBy != "alice" {
+		t.Fatalf("unexpected identity %q %q after create", exists.CreatedBy, exists.UpdatedBy)
+	}
+
+	exists := get()
+	exists.CreatedBy, exists.UpdatedBy = "mallory", "mallory"
+	if err := etcdStore.Update(ctx, exists); err != nil {
+		t.Fatalf("failed to update object: %v", err)
+	}
+	if exists := get(); exists.CreatedBy != "alice" || exists.UpdatedBy != "alice" {
+		t.Fatalf("unexpected identity %q %q after update without identity", exists.CreatedBy, exists.UpdatedBy)
+	}
+
+	exists = get()
+	if err := etcdStore.Update(store.WithIdentity(ctx, "bob"), exists); err != nil {
+		t.Fatalf("failed to update object: %v", err)
+	}
+	if exists := get(); exists.CreatedBy != "alice" || exists.UpdatedBy != "bob" {
+		t.Fatalf("unexpected identity %q %q after update", exists.CreatedBy, exists.UpdatedBy)
+	}
+
+	patch := store.RawPatch(store.PatchTypeMergePatch, []byte(`{"createdBy":"mallory","updatedBy":"mallory"}`))
+	if err := etcdStore.Patch(ctx, get(), patch); err != nil {
+		t.Fatalf("failed to patch object: %v", err)
+	}
+	if exists := get(); exists.CreatedBy != "alice" || exists.UpdatedBy != "bob" {
+		t.Fatalf("unexpected identity %q %q after patch without identity", exists.CreatedBy, exists.UpdatedBy)
+	}
+}
+
+func TestEtcdStoreRequirements(t *testing.T) {
+	ctx := context.Background()
+	etcdStore := SetupEtcdTestEtcdStore(t)
+
+	newLock := func() *store.Unstructured {
+		lock := &store.Unstructured{Object: map[string]any{"holder": "h1"}}
+		lock.SetResource("locks")
+		lock.SetID("test")
+		return lock
+	}
+	if err := etcdStore.Create(ctx, newLock()); err != nil {
+		t.Fatalf("failed to create object: %v", err)
+	}
+
+	mismatch := store.RequirementEqual("holder", "h2")
+	patch := store.MapMergePatch{"holder": "h3"}
+	if err := etcdStore.Patch(ctx, newLock(), patch, store.WithPatchFieldRequirements(mismatch)); !errors.IsNotFound(err) {
+		t.Fatalf("expected not found on patch with mismatched requirements, got %v", err)
+	}
+	// with finalizers
+	if err := etcdStore.Delete(ctx, newLock(), store.WithDeleteFieldRequirements(mismatch)); !errors.IsNotFound(err) {
+		t.Fatalf("expected not found on delete with mismatched requirements, got %v", err)
+	}
+	// without finalizers
+	background := store.WithDeletePropagation(store.DeletePropagationBackground)
+	if err := etcdStore.Delete(ctx, newLock(), background, store.WithDeleteLabelRequirements(store.RequirementEqual("app", "web"))); !errors.IsNotFound(err) {
+		t.Fatalf("expected not found on delete with mismatched label requirements, got %v", err)
+	}
+	exists := newLock()
+	if err := etcdStore.Get(ctx, "test", exists); err != nil {
+		t.Fatalf("object should be kept: %v", err)
+	}
+	if holder := exists.GetNestedString("holder"); holder != "h1" || exists.GetDeletionTimestamp() != nil {
+		t.Fatalf("unexpected holder %q or deletion timestamp %v", holder, exists.GetDeletionTimestamp())
+	}
+
+	match := store.RequirementEqual("holder", "h1")
+	if err := etcdStore.Patch(ctx, newLock(), store.MapMergePatch{"holder": "h2"}, store.WithPatchFieldRequirements(match)); err != nil {
+		t.Fatalf("failed to patch object: %v", err)
+	}
+	if err := etcdStore.Delete(ctx, newLock(), background, store.WithDeleteFieldRequirements(mismatch)); err != nil {
+		t.Fatalf("failed to delete object: %v", err)
+	}
+	if err := etcdStore.Get(ctx, "test", newLock()); !errors.IsNotFound(err) {
+		t.Fatalf("expected object deleted, got %v", err)
+	}
+}
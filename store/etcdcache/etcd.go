@@ -186,6 +186,7 @@ func (c *generic) Create(ctx context.Context, obj store.Object, opts ...store.Cr
 		obj.SetUID(uuid.New().String())
 		obj.SetCreationTimestamp(libmeta.Now())
 		obj.SetGeneration(1)
+		store.SetCreatedBy(ctx, obj)
 		obj.SetScopes(c.scopes)
 		obj.SetResource(db.resource.String())
 		uns, err := ConvertToUnstructured(obj)
@@ -286,6 +287,9 @@ func (c *generic) List(ctx context.Context, list store.ObjectList, opts ...store
 		return err
 	}
 	return c.core.on(ctx, list, func(ctx context.Context, db *db) error {
+		if err := store.ApplyListPolicy(db.resource.String(), &options); err != nil {
+			return err
+		}
 		keyprefix := getlistkey(c.scopes, db.resource.String())
 		listopts := storage.ListOptions{
 			Recursive:       true,
@@ -535,9 +539,6 @@ func convertError(err error) error {
 type updateFunc func(ctx context.Context, current *store.Unstructured) (newObj store.Object, err error)
 
 func (c *core) update(ctx context.Context, scopes []store.Scope, obj store.Object, preconditions *storage.Preconditions, predicate storage.SelectionPredicate, fn updateFunc, statusOnly bool) error {
-	if !predicate.Empty() {
-		return errors.NewBadRequest("predicate is not supported")
-	}
 	return c.on(ctx, obj, func(ctx context.Context, db *db) error {
 		out := &StorageObject{}
 		key := getObjectKey(scopes, db.resource.String(), obj.GetID())
@@ -546,6 +547,16 @@ func (c *core) update(ctx context.Context, scopes []store.Scope, obj store.Objec
 			if !ok {
 				return nil, nil, fmt.Errorf("unexpected object type: %T", input)
 			}
+			// requirements are checked on the object to be updated, as other backends filtering by them do
+			if !predicate.Empty() {
+				matched, err := predicate.Matches(current)
+				if err != nil {
+					return nil, nil, err
+				}
+				if !matched {
+					return nil, nil, apierrors.NewNotFound(db.resource, obj.GetID())
+				}
+			}
 			// backup fields
 			statusfield, _, _ := unstructured.NestedFieldNoCopy(current.Object, "status")
 			unsobj := &store.Unstructured{}
@@ -553,6 +564,7 @@ func (c *core) update(ctx context.Context, scopes []store.Scope, obj store.Objec
 				return nil, nil, err
 			}
 			scopes, id, uid, creation, deletion, generation := unsobj.GetScopes(), unsobj.GetID(), unsobj.GetUID(), unsobj.GetCreationTimestamp(), unsobj.GetDeletionTimestamp(), unsobj.GetGeneration()
+			createdBy, updatedBy := unsobj.GetCreatedBy(), unsobj.GetUpdatedBy()
 			unsobjchanged, err := fn(ctx, unsobj)
 			if err != nil {
 				return nil, nil, err
@@ -563,6 +575,8 @@ func (c *core) update(ctx context.Context, scopes []store.Scope, obj store.Objec
 			unsobjchanged.SetUID(uid)
 			unsobjchanged.SetResource(db.resource.String())
 			unsobjchanged.SetCreationTimestamp(creation)
+			unsobjchanged.SetCreatedBy(createdBy)
+			store.SetUpdatedBy(ctx, unsobjchanged, updatedBy)
 			// once deletiontime is set, it can not be updated
 			if deletion != nil {
 				unsobjchanged.SetDeletionTimestamp(deletion)
@@ -756,3 +756,98 @@ var (
 	_ = labels.Everything
 	_ = fields.Everything
 )
+
+func TestIdentity(t *testing.T) {
+	cli := testserver.RunEtcd(t, nil)
+	s, err := NewEtcdCacherFromClient(cli, "/test", nil)
+	if err != nil {
+		t.Fatalf("Failed to create etcd cacher: %v", err)
+	}
+	ctx := context.Background()
+	get := func() *MyObject {
+		t.Helper()
+		exists := &MyObject{}
+		if err := s.Get(ctx, "test", exists); err != nil {
+			t.Fatalf("Failed to get object: %v", err)
+		}
+		return exists
+	}
+
+	// values sent by clients are ignored
+	obj := &MyObject{ObjectMeta: store.ObjectMeta{ID: "test", Name: "test", CreatedBy: "mallory", UpdatedBy: "mallory"}}
+	if err := s.Create(store.WithIdentity(ctx, "alice"), obj); err != nil {
+		t.Fatalf("Failed to create object: %v", err)
+	}
+	if exists := get(); exists.CreatedBy != "alice" || exists.UpdatedBy != "alice" {
+		t.Fatalf("Unexpected identity %q %q after create", exists.CreatedBy, exists.UpdatedBy)
+	}
+
+	exists := get()
+	exists.CreatedBy, exists.UpdatedBy = "mallory", "mallory"
+	if err := s.Update(ctx, exists); err != nil {
+		t.Fatalf("Failed to update object: %v", err)
+	}
+	if exists := get(); exists.CreatedBy != "alice" || exists.UpdatedBy != "alice" {
+		t.Fatalf("Unexpected identity %q %q after update without identity", exists.CreatedBy, exists.UpdatedBy)
+	}
+
+	if err := s.Update(store.WithIdentity(ctx, "bob"), get()); err != nil {
+		t.Fatalf("Failed to update object: %v", err)
+	}
+	if exists := get(); exists.CreatedBy != "alice" || exists.UpdatedBy != "bob" {
+		t.Fatalf("Unexpected identity %q %q after update", exists.CreatedBy, exists.UpdatedBy)
+	}
+
+	patch := store.RawPatch(store.PatchTypeMergePatch, []byte(`{"createdBy":"mallory","updatedBy":"mallory"}`))
+	if err := s.Patch(ctx, get(), patch); err != nil {
+		t.Fatalf("Failed to patch object: %v", err)
+	}
+	if exists := get(); exists.CreatedBy != "alice" || exists.UpdatedBy != "bob" {
+		t.Fatalf("Unexpected identity %q %q after patch without identity", exists.CreatedBy, exists.UpdatedBy)
+	}
+}
+
+func TestEtcdCacherRequirements(t *testing.T) {
+	cli := testserver.RunEtcd(t, nil)
+	s, err := NewEtcdCacherFromClient(cli, "/test", nil)
+	if err != nil {
+		t.Fatalf("Failed to create etcd cacher: %v", err)
+	}
+	ctx := context.Background()
+	newObj := func() *MyObject {
+		return &MyObject{ObjectMeta: store.ObjectMeta{ID: "test", Name: "test"}, Spec: MyObjectSpec{Value: "v1"}}
+	}
+	if err := s.Create(ctx, newObj()); err != nil {
+		t.Fatalf("Failed to create object: %v", err)
+	}
+
+	mismatch := store.RequirementEqual("spec.value", "v2")
+	patch := store.MapMergePatch{"spec": map[string]any{"value": "v3"}}
+	if err := s.Patch(ctx, newObj(), patch, store.WithPatchFieldRequirements(mismatch)); !errors.IsNotFound(err) {
+		t.Fatalf("Expected not found on patch with mismatched requirements, got %v", err)
+	}
+	if err := s.Delete(ctx, newObj(), store.WithDeleteFieldRequirements(mismatch)); !errors.IsNotFound(err) {
+		t.Fatalf("Expected not found on delete with mismatched requirements, got %v", err)
+	}
+	if err := s.Delete(ctx, newObj(), store.WithDeleteLabelRequirements(store.RequirementEqual("app", "web"))); !errors.IsNotFound(err) {
+		t.Fatalf("Expected not found on delete with mismatched label requirements, got %v", err)
+	}
+	exists := &MyObject{}
+	if err := s.Get(ctx, "test", exists); err != nil {
+		t.Fatalf("Object should be kept: %v", err)
+	}
+	if exists.Spec.Value != "v1" || exists.DeletionTimestamp != nil {
+		t.Fatalf("Unexpected object %+v", exists)
+	}
+
+	match := store.RequirementEqual("spec.value", "v1")
+	if err := s.Patch(ctx, newObj(), store.MapMergePatch{"spec": map[string]any{"value": "v2"}}, store.WithPatchFieldRequirements(match)); err != nil {
+		t.Fatalf("Failed to patch object: %v", err)
+	}
+	if err := s.Delete(ctx, newObj(), store.WithDeleteFieldRequirements(mismatch)); err != nil {
+		t.Fatalf("Failed to delete object: %v", err)
+	}
+	if err := s.Get(ctx, "test", &MyObject{}); !errors.IsNotFound(err) {
+		t.Fatalf("Expected object deleted, got %v", err)
+	}
+}
@@ -42,8 +42,10 @@ func GetAttrsFunc(indexfields []string) func(obj runtime.Object) (labels.Set, fi
 			return nil, nil, fmt.Errorf("unexpected object type: %T", obj)
 		}
 		sFields := fields.Set{
-			"id":   GetNestedString(uns.Object, "id"),
-			"name": GetNestedString(uns.Object, "name"),
+			"id":        GetNestedString(uns.Object, "id"),
+			"name":      GetNestedString(uns.Object, "name"),
+			"createdBy": GetNestedString(uns.Object, "createdBy"),
+			"updatedBy": GetNestedString(uns.Object, "updatedBy"),
 		}
 		for _, fname := range indexfields {
 			val, ok := getFieldIndex(uns, strings.Split(fname, ".")...)
@@ -498,6 +498,7 @@ func ListOptionsFromMetaListOptions(reqlistopetions meta.ListOptions) ([]ListOpt
 		WithPageSize(reqlistopetions.Page, reqlistopetions.Size),
 		WithSort(reqlistopetions.Sort),
 		WithSearch(reqlistopetions.Search),
+		WithContinue(reqlistopetions.Continue),
 	}
 	labelsSelector, err := ParseRequirements(reqlistopetions.LabelSelector)
 	if err != nil {
@@ -515,3 +516,21 @@ func ListOptionsFromMetaListOptions(reqlistopetions meta.ListOptions) ([]ListOpt
 	}
 	return reqOptions, nil
 }
+
+// ValidateBatchRequirements prevents batch operations from affecting all objects in a scope by accident,
+// it returns an error if there is no requirement and unfiltered operation is not explicitly allowed.
+func ValidateBatchRequirements(labels, fields Requirements, allowUnfiltered bool) error {
+	if len(labels) == 0 && len(fields) == 0 && !allowUnfiltered {
+		return errors.NewBadRequest("batch operation requires at least one label or field requirement")
+	}
+	return nil
+}
+
+// ValidateBatchAffected returns an error if the number of objects to be affected exceeds max.
+// max 0 means no limit.
+func ValidateBatchAffected(count, max int) error {
+	if max > 0 && count > max {
+		return errors.NewBadRequest(fmt.Sprintf("batch operation would affect %d objects, exceeds the limit %d", count, max))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"net/http"
+	"testing"
+
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/meta"
+)
+
+func TestValidateBatchRequirements(t *testing.T) {
+	selector := Requirements{RequirementEqual("app", "web")}
+	tests := []struct {
+		name            string
+		labels          Requirements
+		fields          Requirements
+		allowUnfiltered bool
+		wantErr         bool
+	}{
+		{name: "unfiltered", wantErr: true},
+		{name: "empty requirements", labels: Requirements{}, fields: Requirements{}, wantErr: true},
+		{name: "allow unfiltered", allowUnfiltered: true},
+		{name: "labels", labels: selector},
+		{name: "fields", fields: selector},
+		{name: "filtered and allow unfiltered", labels: selector, allowUnfiltered: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBatchRequirements(tt.labels, tt.fields, tt.allowUnfiltered)
+			if tt.wantErr {
+				if !errors.IsCode(err, http.StatusBadRequest) {
+					t.Errorf("ValidateBatchRequirements() error = %v, want bad request", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ValidateBatchRequirements() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateBatchAffected(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		max     int
+		wantErr bool
+	}{
+		{name: "no limit", count: 1000},
+		{name: "below limit", count: 9, max: 10},
+		{name: "at limit", count: 10, max: 10},
+		{name: "exceeds limit", count: 11, max: 10, wantErr: true},
+		{name: "nothing affected", max: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBatchAffected(tt.count, tt.max)
+			if tt.wantErr {
+				if !errors.IsCode(err, http.StatusBadRequest) {
+					t.Errorf("ValidateBatchAffected() error = %v, want bad request", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ValidateBatchAffected() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestListOptionsFromMetaListOptions(t *testing.T) {
+	opts, err := ListOptionsFromMetaListOptions(meta.ListOptions{
+		Page:          2,
+		Size:          10,
+		Search:        "web",
+		Sort:          "name-",
+		Continue:      "token",
+		LabelSelector: "app=web",
+		FieldSelector: "status.phase!=Running",
+	})
+	if err != nil {
+		t.Fatalf("ListOptionsFromMetaListOptions() error = %v", err)
+	}
+	got := ListOptions{}
+	for _, opt := range opts {
+		opt(&got)
+	}
+	if got.Page != 2 || got.Size != 10 || got.Search != "web" || got.Sort != "name-" || got.Continue != "token" {
+		t.Errorf("ListOptionsFromMetaListOptions() = %+v", got)
+	}
+	if got.LabelRequirements.String() != "app=web" || got.FieldRequirements.String() != "status.phase!=Running" {
+		t.Errorf("ListOptionsFromMetaListOptions() requirements = %s, %s", got.LabelRequirements, got.FieldRequirements)
+	}
+
+	if _, err := ListOptionsFromMetaListOptions(meta.ListOptions{LabelSelector: "app in (web"}); err == nil {
+		t.Errorf("ListOptionsFromMetaListOptions() with invalid selector should fail")
+	}
+}
@@ -0,0 +1,36 @@
+package store
+
+import "context"
+
+type identityContextKey struct{}
+
+// WithIdentity returns a context carries the identity of the caller, e.g. the authenticated username.
+// Stores record it as [ObjectMeta.CreatedBy] and [ObjectMeta.UpdatedBy] of objects written with the context.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// IdentityFromContext returns the identity set by [WithIdentity], empty if not set.
+func IdentityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(identityContextKey{}).(string)
+	return identity
+}
+
+// SetCreatedBy sets both CreatedBy and UpdatedBy of a new object from the identity of the context.
+// They are cleared if the context has no identity, e.g. objects created by controllers,
+// values sent by clients are never stored.
+func SetCreatedBy(ctx context.Context, obj Object) {
+	identity := IdentityFromContext(ctx)
+	obj.SetCreatedBy(identity)
+	obj.SetUpdatedBy(identity)
+}
+
+// SetUpdatedBy sets UpdatedBy of an updated object from the identity of the context,
+// or to previous, the stored value, if the context has no identity.
+func SetUpdatedBy(ctx context.Context, obj Object, previous string) {
+	if identity := IdentityFromContext(ctx); identity != "" {
+		obj.SetUpdatedBy(identity)
+	} else {
+		obj.SetUpdatedBy(previous)
+	}
+}
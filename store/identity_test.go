@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetCreatedBy(t *testing.T) {
+	obj := &Unstructured{}
+	SetCreatedBy(context.Background(), obj)
+	if obj.GetCreatedBy() != "" || obj.Object["createdBy"] != nil {
+		t.Errorf("unexpected createdBy %v without identity", obj.Object)
+	}
+	// values sent by clients are not stored
+	obj.SetCreatedBy("mallory")
+	obj.SetUpdatedBy("mallory")
+	SetCreatedBy(context.Background(), obj)
+	if obj.GetCreatedBy() != "" || obj.GetUpdatedBy() != "" {
+		t.Errorf("unexpected client identity %v without identity", obj.Object)
+	}
+	ctx := WithIdentity(context.Background(), "alice")
+	SetCreatedBy(ctx, obj)
+	if obj.GetCreatedBy() != "alice" || obj.GetUpdatedBy() != "alice" {
+		t.Errorf("unexpected identity %v", obj.Object)
+	}
+	SetUpdatedBy(WithIdentity(ctx, "bob"), obj, "alice")
+	if obj.GetCreatedBy() != "alice" || obj.GetUpdatedBy() != "bob" {
+		t.Errorf("unexpected identity %v", obj.Object)
+	}
+	obj.SetUpdatedBy("mallory")
+	SetUpdatedBy(context.Background(), obj, "bob")
+	if obj.GetUpdatedBy() != "bob" {
+		t.Errorf("updatedBy = %q, want the previous value", obj.GetUpdatedBy())
+	}
+	obj.SetUpdatedBy("")
+	if _, ok := obj.Object["updatedBy"]; ok {
+		t.Errorf("updatedBy should be removed")
+	}
+}
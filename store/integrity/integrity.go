@@ -0,0 +1,426 @@
+package integrity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"k8s.io/client-go/util/retry"
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/log"
+	"xiaoshiai.cn/common/store"
+)
+
+// AnnotationChecksum is the annotation holds the checksum of the object.
+const AnnotationChecksum = "store.xiaoshiai.cn/checksum"
+
+const (
+	AlgorithmSHA256     = "sha256"
+	AlgorithmHMACSHA256 = "hmac-sha256"
+)
+
+const StatusReasonCorrupted errors.StatusReason = "Corrupted"
+
+type FailurePolicy string
+
+const (
+	// FailurePolicyReject returns an error when a corrupted object is read.
+	FailurePolicyReject FailurePolicy = "Reject"
+	// FailurePolicyWarn logs and counts corrupted objects but returns them.
+	FailurePolicyWarn FailurePolicy = "Warn"
+)
+
+// DefaultIgnoredFields are fields maintained by backends or written by other paths(status subresource),
+// they are not covered by the checksum.
+// Scopes are covered as scope fields, see [Checksummer.Sum].
+var DefaultIgnoredFields = []string{
+	"resource", "resourceVersion", "generation", "uid", "creationTimestamp", "deletionTimestamp",
+	"updationTimestamp", "creator", "createdBy", "updatedBy", "status", "finalizers", "scopes",
+}
+
+type Options struct {
+	// Key enables HMAC-SHA256 with the key instead of plain SHA256,
+	// so the checksum can not be forged by someone who can write the backend directly.
+	Key string `json:"key,omitempty" description:"HMAC key, use plain sha256 checksum if empty"`
+	// FailurePolicy is the policy on reading a corrupted object.
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty" description:"policy on reading a corrupted object, Reject or Warn"`
+	// AllowMissing accepts objects without checksum, e.g. written before integrity protection is enabled.
+	AllowMissing bool `json:"allowMissing,omitempty" description:"accept objects without checksum"`
+	// IgnoredFields are top-level fields not covered by the checksum, default to [DefaultIgnoredFields].
+	IgnoredFields []string `json:"ignoredFields,omitempty" description:"top-level fields not covered by the checksum"`
+}
+
+func NewDefaultOptions() *Options {
+	return &Options{
+		FailurePolicy: FailurePolicyReject,
+		AllowMissing:  true,
+	}
+}
+
+// Checksummer computes and verifies checksums of objects.
+type Checksummer struct {
+	key           []byte
+	ignoredFields []string
+}
+
+func NewChecksummer(options *Options) *Checksummer {
+	ignored := options.IgnoredFields
+	if ignored == nil {
+		ignored = DefaultIgnoredFields
+	}
+	return &Checksummer{key: []byte(options.Key), ignoredFields: ignored}
+}
+
+// Sum returns the checksum of the canonical JSON of the object, e.g. "sha256:<hex>".
+//
+// Backends keep the scopes of an object in different forms, e.g. etcd sets "scopes" and mongo writes
+// scope fields like "tenant", so the canonical form has the scope fields of the scopes of the object,
+// or of scopes if the object does not carry them, e.g. before it is written to a store of scopes.
+func (c *Checksummer) Sum(obj store.Object, scopes ...store.Scope) (string, error) {
+	data, err := c.canonical(obj, scopes)
+	if err != nil {
+		return "", err
+	}
+	if len(c.key) == 0 {
+		sum := sha256.Sum256(data)
+		return AlgorithmSHA256 + ":" + hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(data)
+	return AlgorithmHMACSHA256 + ":" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Sign sets the checksum annotation of the object to write into scopes.
+func (c *Checksummer) Sign(obj store.Object, scopes ...store.Scope) error {
+	sum, err := c.Sum(obj, scopes...)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationChecksum] = sum
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// Verify verifies the checksum annotation of the object read from scopes.
+// It returns ok false if the checksum is missing.
+func (c *Checksummer) Verify(obj store.Object, scopes ...store.Scope) (ok bool, err error) {
+	expected := obj.GetAnnotations()[AnnotationChecksum]
+	if expected == "" {
+		return false, nil
+	}
+	sum, err := c.Sum(obj, scopes...)
+	if err != nil {
+		return true, err
+	}
+	if subtle.ConstantTimeCompare([]byte(sum), []byte(expected)) != 1 {
+		return true, NewCorrupted(obj, "checksum mismatch")
+	}
+	return true, nil
+}
+
+// canonical returns the JSON of the object without ignored fields and the checksum annotation.
+// encoding/json sorts map keys, so the output is stable.
+func (c *Checksummer) canonical(obj store.Object, scopes []store.Scope) ([]byte, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	for _, field := range c.ignoredFields {
+		delete(data, field)
+	}
+	if annotations, ok := data["annotations"].(map[string]any); ok {
+		delete(annotations, AnnotationChecksum)
+		if len(annotations) == 0 {
+			delete(data, "annotations")
+		}
+	}
+	if objscopes := obj.GetScopes(); len(objscopes) > 0 {
+		scopes = objscopes
+	}
+	store.SetScopesFields(data, scopes)
+	return json.Marshal(data)
+}
+
+func NewCorrupted(obj store.Object, message string) *errors.Status {
+	return errors.NewCustomError(http.StatusInternalServerError, StatusReasonCorrupted,
+		fmt.Sprintf("object %s %q is corrupted: %s", obj.GetResource(), obj.GetID(), message))
+}
+
+func IsCorrupted(err error) bool {
+	return errors.ReasonForError(err) == StatusReasonCorrupted
+}
+
+var _ store.Store = &IntegrityStore{}
+
+// IntegrityStore signs objects on write and verifies them on read.
+//
+// Create and Update sign the object before writing. Patch and PatchBatch apply patches to the current objects
+// and write them signed with updates, the resource version read is the precondition of the update,
+// so backends must reject updates of stale resource versions, as the etcd stores do.
+// Status writes and watch events are not verified, status is not covered by the checksum by default.
+//
+// Backends must store objects faithfully, e.g. a backend truncating time precision of custom fields
+// makes the objects fail verification.
+type IntegrityStore struct {
+	Store       store.Store
+	Options     *Options
+	Checksummer *Checksummer
+	scopes      []store.Scope
+	corrupted   metric.Int64Counter
+}
+
+func NewIntegrityStore(s store.Store, options *Options) *IntegrityStore {
+	meter := otel.Meter("xiaoshiai.cn/common/store")
+	corrupted, err := meter.Int64Counter("store.integrity.corrupted",
+		metric.WithDescription("Number of corrupted objects detected by integrity verification."))
+	if err != nil {
+		otel.Handle(err)
+	}
+	return &IntegrityStore{Store: s, Options: options, Checksummer: NewChecksummer(options), corrupted: corrupted}
+}
+
+// verify applies the failure policy on obj.
+func (s *IntegrityStore) verify(ctx context.Context, obj store.Object) error {
+	signed, err := s.Checksummer.Verify(obj, s.scopes...)
+	if err == nil && !signed && !s.Options.AllowMissing {
+		err = NewCorrupted(obj, "checksum is missing")
+	}
+	if err == nil {
+		return nil
+	}
+	s.corrupted.Add(ctx, 1, metric.WithAttributes(attribute.String("resource", obj.GetResource())))
+	if s.Options.FailurePolicy == FailurePolicyWarn {
+		log.FromContext(ctx).Error(err, "integrity verification failed", "resource", obj.GetResource(), "id", obj.GetID())
+		return nil
+	}
+	return err
+}
+
+// Create implements store.Store.
+func (s *IntegrityStore) Create(ctx context.Context, obj store.Object, opts ...store.CreateOption) error {
+	if err := s.Checksummer.Sign(obj, s.scopes...); err != nil {
+		return err
+	}
+	return s.Store.Create(ctx, obj, opts...)
+}
+
+// Update implements store.Store.
+func (s *IntegrityStore) Update(ctx context.Context, obj store.Object, opts ...store.UpdateOption) error {
+	if err := s.Checksummer.Sign(obj, s.scopes...); err != nil {
+		return err
+	}
+	return s.Store.Update(ctx, obj, opts...)
+}
+
+// Patch implements store.Store.
+func (s *IntegrityStore) Patch(ctx context.Context, obj store.Object, patch store.Patch, opts ...store.PatchOption) error {
+	options := store.PatchOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+	return s.patch(ctx, obj, store.RawPatch(patch.Type(), data), options)
+}
+
+// patch applies the patch to the current object and writes it signed with an update,
+// so the data and its checksum are written at once.
+// The update has the resource version read as precondition, it is retried on conflict.
+func (s *IntegrityStore) patch(ctx context.Context, obj store.Object, patch store.Patch, options store.PatchOptions) error {
+	resource, id := obj.GetResource(), obj.GetID()
+	return retry.OnError(retry.DefaultRetry, errors.IsConflict, func() error {
+		// reset the object patched in the previous attempt, decoding merges into maps
+		v := reflect.ValueOf(obj).Elem()
+		v.Set(reflect.Zero(v.Type()))
+		if err := s.Store.Get(ctx, id, obj); err != nil {
+			return err
+		}
+		obj.SetResource(resource)
+		// do not sign corrupted objects
+		if err := s.verify(ctx, obj); err != nil {
+			return err
+		}
+		matched, err := matchRequirements(obj, options.LabelRequirements, options.FieldRequirements)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return errors.NewNotFound(resource, id)
+		}
+		resourceVersion := obj.GetResourceVersion()
+		if err := store.ApplyPatch(obj, obj, patch); err != nil {
+			return err
+		}
+		obj.SetResource(resource)
+		obj.SetID(id)
+		obj.SetResourceVersion(resourceVersion)
+		if err := s.Checksummer.Sign(obj, s.scopes...); err != nil {
+			return err
+		}
+		return s.Store.Update(ctx, obj, func(uo *store.UpdateOptions) {
+			uo.LabelRequirements = options.LabelRequirements
+			uo.FieldRequirements = options.FieldRequirements
+			uo.DryRun = options.DryRun
+		})
+	})
+}
+
+func matchRequirements(obj store.Object, labels, fields store.Requirements) (bool, error) {
+	if !store.MatchLabelReqirements(obj, labels) {
+		return false, nil
+	}
+	if len(fields) == 0 {
+		return true, nil
+	}
+	uns, err := store.ToUnstructured(obj)
+	if err != nil {
+		return false, err
+	}
+	return store.MatchUnstructuredFieldRequirments(uns, fields), nil
+}
+
+// PatchBatch implements store.Store.
+//
+// Matching objects are listed and patched one by one as [IntegrityStore.Patch] does,
+// objects changed to no longer match or deleted in between are skipped.
+// Unlike a backend batch patch, it is not atomic.
+func (s *IntegrityStore) PatchBatch(ctx context.Context, obj store.ObjectList, patch store.PatchBatch, opts ...store.PatchBatchOption) error {
+	options := store.PatchBatchOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if err := store.ValidateBatchRequirements(options.LabelRequirements, options.FieldRequirements, options.AllowUnfiltered); err != nil {
+		return err
+	}
+	resource := obj.GetResource()
+	items := []*store.Unstructured{}
+	scanner := &Scanner{Store: s.Store, Checksummer: s.Checksummer, Scopes: s.scopes}
+	if err := scanner.each(ctx, resource, func(item store.Object) error {
+		items = append(items, item.(*store.Unstructured))
+		return nil
+	}, store.WithLabelRequirements(options.LabelRequirements...), store.WithFieldRequirements(options.FieldRequirements...)); err != nil {
+		return err
+	}
+	if err := store.ValidateBatchAffected(len(items), options.MaxAffected); err != nil {
+		return err
+	}
+	patchoptions := store.PatchOptions{
+		LabelRequirements: options.LabelRequirements,
+		FieldRequirements: options.FieldRequirements,
+		DryRun:            options.DryRun,
+	}
+	rawpatch := store.RawPatch(patch.Type(), patch.Data())
+	patched := 0
+	for _, item := range items {
+		item.SetResource(resource)
+		if err := s.patch(ctx, item, rawpatch, patchoptions); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		patched++
+	}
+	obj.SetTotal(patched)
+	return nil
+}
+
+// Get implements store.Store.
+func (s *IntegrityStore) Get(ctx context.Context, id string, obj store.Object, opts ...store.GetOption) error {
+	if err := s.Store.Get(ctx, id, obj, opts...); err != nil {
+		return err
+	}
+	options := store.GetOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if len(options.Fields) > 0 {
+		// partial objects can not be verified
+		return nil
+	}
+	return s.verify(ctx, obj)
+}
+
+// List implements store.Store.
+func (s *IntegrityStore) List(ctx context.Context, list store.ObjectList, opts ...store.ListOption) error {
+	if err := s.Store.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	options := store.ListOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if len(options.Fields) > 0 {
+		return nil
+	}
+	items, _, err := store.NewItemFuncFromList(list)
+	if err != nil {
+		return err
+	}
+	for i := range items.Len() {
+		item, ok := items.Index(i).Addr().Interface().(store.Object)
+		if !ok {
+			continue
+		}
+		if err := s.verify(ctx, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count implements store.Store.
+func (s *IntegrityStore) Count(ctx context.Context, obj store.Object, opts ...store.CountOption) (int, error) {
+	return s.Store.Count(ctx, obj, opts...)
+}
+
+// Delete implements store.Store.
+func (s *IntegrityStore) Delete(ctx context.Context, obj store.Object, opts ...store.DeleteOption) error {
+	return s.Store.Delete(ctx, obj, opts...)
+}
+
+// DeleteBatch implements store.Store.
+func (s *IntegrityStore) DeleteBatch(ctx context.Context, obj store.ObjectList, opts ...store.DeleteBatchOption) error {
+	return s.Store.DeleteBatch(ctx, obj, opts...)
+}
+
+// Watch implements store.Store.
+func (s *IntegrityStore) Watch(ctx context.Context, obj store.ObjectList, opts ...store.WatchOption) (store.Watcher, error) {
+	return s.Store.Watch(ctx, obj, opts...)
+}
+
+// Status implements store.Store.
+func (s *IntegrityStore) Status() store.StatusStorage {
+	return s.Store.Status()
+}
+
+// Scope implements store.Store.
+func (s *IntegrityStore) Scope(scope ...store.Scope) store.Store {
+	return &IntegrityStore{
+		Store:       s.Store.Scope(scope...),
+		Options:     s.Options,
+		Checksummer: s.Checksummer,
+		scopes:      append(slices.Clone(s.scopes), scope...),
+		corrupted:   s.corrupted,
+	}
+}
@@ -0,0 +1,236 @@
+package integrity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/store"
+)
+
+// mapStore stores objects as JSON by id.
+// Objects are written with their scopes as etcd does, or with scope fields as mongo does if scopeFields is set.
+// Update rejects stale resource versions, afterGet is called after every Get.
+type mapStore struct {
+	store.Store
+	data        map[string][]byte
+	scopes      []store.Scope
+	scopeFields bool
+	afterGet    func()
+}
+
+func (m *mapStore) Create(ctx context.Context, obj store.Object, opts ...store.CreateOption) error {
+	obj.SetResourceVersion(1)
+	return m.write(obj)
+}
+
+func (m *mapStore) Update(ctx context.Context, obj store.Object, opts ...store.UpdateOption) error {
+	current := &store.Unstructured{}
+	if err := m.Get(ctx, obj.GetID(), current); err != nil {
+		return err
+	}
+	if rv := obj.GetResourceVersion(); rv != 0 && rv != current.GetResourceVersion() {
+		return errors.NewConflict("objects", obj.GetID(), fmt.Errorf("resourceVersion %d does not match", rv))
+	}
+	obj.SetResourceVersion(current.GetResourceVersion() + 1)
+	return m.write(obj)
+}
+
+func (m *mapStore) write(obj store.Object) error {
+	if len(m.scopes) > 0 && !m.scopeFields {
+		obj.SetScopes(m.scopes)
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if m.scopeFields {
+		fields := map[string]any{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		store.SetScopesFields(fields, m.scopes)
+		if data, err = json.Marshal(fields); err != nil {
+			return err
+		}
+	}
+	m.data[obj.GetID()] = data
+	return nil
+}
+
+func (m *mapStore) Scope(scope ...store.Scope) store.Store {
+	return &mapStore{data: m.data, scopes: append(slices.Clone(m.scopes), scope...), scopeFields: m.scopeFields}
+}
+
+func (m *mapStore) Get(ctx context.Context, id string, obj store.Object, opts ...store.GetOption) error {
+	data, ok := m.data[id]
+	if !ok {
+		return errors.NewNotFound("objects", id)
+	}
+	if m.afterGet != nil {
+		defer m.afterGet()
+	}
+	return json.Unmarshal(data, obj)
+}
+
+func (m *mapStore) List(ctx context.Context, list store.ObjectList, opts ...store.ListOption) error {
+	l := list.(*store.List[store.Unstructured])
+	for _, data := range m.data {
+		item := store.Unstructured{}
+		if err := json.Unmarshal(data, &item); err != nil {
+			return err
+		}
+		l.Items = append(l.Items, item)
+	}
+	l.Total = len(l.Items)
+	return nil
+}
+
+func TestIntegrityStore(t *testing.T) {
+	ctx := context.Background()
+	backend := &mapStore{data: map[string][]byte{}}
+	options := NewDefaultOptions()
+	options.Key = "secret"
+	s := NewIntegrityStore(backend, options)
+
+	obj := &store.ObjectMeta{ID: "foo", Labels: map[string]string{"a": "b"}}
+	if err := s.Create(ctx, obj); err != nil {
+		t.Fatal(err)
+	}
+	if got := obj.Annotations[AnnotationChecksum]; !strings.HasPrefix(got, AlgorithmHMACSHA256+":") {
+		t.Fatalf("unexpected checksum %q", got)
+	}
+
+	// backend maintained fields are not covered
+	into := &store.ObjectMeta{}
+	if err := s.Get(ctx, "foo", into); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	into.ResourceVersion = 10
+	if ok, err := s.Checksummer.Verify(into); !ok || err != nil {
+		t.Errorf("Verify() = %v, %v, ignored fields should not affect checksum", ok, err)
+	}
+
+	// silent corruption
+	backend.data["foo"] = []byte(`{"id":"foo","labels":{"a":"c"},"annotations":{"` + AnnotationChecksum + `":"` + obj.Annotations[AnnotationChecksum] + `"}}`)
+	if err := s.Get(ctx, "foo", &store.ObjectMeta{}); !IsCorrupted(err) {
+		t.Errorf("Get() error = %v, want corrupted", err)
+	}
+	options.FailurePolicy = FailurePolicyWarn
+	if err := s.Get(ctx, "foo", &store.ObjectMeta{}); err != nil {
+		t.Errorf("Get() error = %v, want nil on warn policy", err)
+	}
+
+	// objects written before protection is enabled
+	backend.data["bar"] = []byte(`{"id":"bar"}`)
+	report, err := NewScanner(backend, options).Scan(ctx, "objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 2 || len(report.Corrupted) != 1 || len(report.Unsigned) != 1 {
+		t.Errorf("unexpected scan report %+v", report)
+	}
+}
+
+func TestIntegrityStoreScoped(t *testing.T) {
+	ctx := context.Background()
+	scopes := []store.Scope{{Resource: "tenants", Name: "t1"}, {Resource: "projects", Name: "p1"}}
+	for _, scopeFields := range []bool{false, true} {
+		backend := &mapStore{data: map[string][]byte{}, scopeFields: scopeFields}
+		s := NewIntegrityStore(backend, NewDefaultOptions())
+		scoped := s.Scope(scopes[0]).Scope(scopes[1])
+
+		if err := scoped.Create(ctx, &store.ObjectMeta{ID: "foo"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := scoped.Get(ctx, "foo", &store.Unstructured{}); err != nil {
+			t.Errorf("Get() unstructured error = %v, scopeFields %v", err, scopeFields)
+		}
+		if err := scoped.Get(ctx, "foo", &store.ObjectMeta{}); err != nil {
+			t.Errorf("Get() error = %v, scopeFields %v", err, scopeFields)
+		}
+		report, err := NewScanner(backend, s.Options).Scan(ctx, "objects")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Scanned != 1 || len(report.Corrupted) != 0 {
+			t.Errorf("unexpected scan report %+v", report)
+		}
+	}
+}
+
+func TestIntegrityStorePatch(t *testing.T) {
+	ctx := context.Background()
+	backend := &mapStore{data: map[string][]byte{}}
+	s := NewIntegrityStore(backend, NewDefaultOptions())
+	if err := s.Create(ctx, &store.ObjectMeta{ID: "foo", Labels: map[string]string{"app": "web"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a concurrent write lands between reading and writing the patched object
+	concurrent := NewIntegrityStore(&mapStore{data: backend.data}, NewDefaultOptions())
+	backend.afterGet = func() {
+		backend.afterGet = nil
+		obj := &store.ObjectMeta{}
+		if err := concurrent.Get(ctx, "foo", obj); err != nil {
+			t.Fatal(err)
+		}
+		obj.Description = "concurrent"
+		if err := concurrent.Update(ctx, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	obj := &store.ObjectMeta{ID: "foo"}
+	if err := s.Patch(ctx, obj, store.MapMergePatch{"labels": map[string]any{"tier": "frontend"}}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	into := &store.ObjectMeta{}
+	if err := s.Get(ctx, "foo", into); err != nil {
+		t.Fatalf("Get() after patch error = %v", err)
+	}
+	if into.Description != "concurrent" || into.Labels["tier"] != "frontend" || into.Labels["app"] != "web" || into.ResourceVersion != 3 {
+		t.Errorf("patched object %+v, want both writes", into)
+	}
+
+	// requirements are checked on the current object
+	err := s.Patch(ctx, &store.ObjectMeta{ID: "foo"}, store.MapMergePatch{"description": "x"},
+		func(po *store.PatchOptions) {
+			po.LabelRequirements = store.Requirements{store.RequirementEqual("app", "api")}
+		})
+	if !errors.IsNotFound(err) {
+		t.Errorf("Patch() with unmatched requirements error = %v, want not found", err)
+	}
+
+	// corrupted objects are not signed
+	backend.data["bad"] = []byte(`{"id":"bad","resourceVersion":1,"annotations":{"` + AnnotationChecksum + `":"sha256:00"}}`)
+	if err := s.Patch(ctx, &store.ObjectMeta{ID: "bad"}, store.MapMergePatch{"description": "x"}); !IsCorrupted(err) {
+		t.Errorf("Patch() of corrupted object error = %v, want corrupted", err)
+	}
+	delete(backend.data, "bad")
+
+	// batch
+	if err := s.Create(ctx, &store.ObjectMeta{ID: "bar", Labels: map[string]string{"app": "api"}}); err != nil {
+		t.Fatal(err)
+	}
+	list := &store.List[store.Unstructured]{}
+	list.SetResource("objects")
+	if err := s.PatchBatch(ctx, list, store.RawPatchBatch(store.PatchTypeMergePatch, []byte(`{"description":"batch"}`)), store.WithPatchBatchAllowUnfiltered()); err != nil {
+		t.Fatalf("PatchBatch() error = %v", err)
+	}
+	if list.Total != 2 {
+		t.Errorf("PatchBatch() total = %d, want 2", list.Total)
+	}
+	for _, id := range []string{"foo", "bar"} {
+		into := &store.ObjectMeta{}
+		if err := s.Get(ctx, id, into); err != nil || into.Description != "batch" {
+			t.Errorf("Get() %s after batch patch = %+v, %v", id, into, err)
+		}
+	}
+	if err := s.PatchBatch(ctx, list, store.RawPatchBatch(store.PatchTypeMergePatch, []byte(`{}`))); err == nil {
+		t.Errorf("PatchBatch() without requirements should fail")
+	}
+}
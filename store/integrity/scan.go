@@ -0,0 +1,77 @@
+package integrity
+
+import (
+	"context"
+
+	"xiaoshiai.cn/common/store"
+)
+
+// ScanReport is the result of scanning a resource.
+type ScanReport struct {
+	Resource  string                  `json:"resource"`
+	Scanned   int                     `json:"scanned"`
+	Corrupted []store.ObjectReference `json:"corrupted,omitempty"`
+	// Unsigned are objects without checksum.
+	Unsigned []store.ObjectReference `json:"unsigned,omitempty"`
+}
+
+// Scanner audits whole resources for silent corruption.
+type Scanner struct {
+	// Store is the underlying store, not the [IntegrityStore], so corrupted objects can be read.
+	Store       store.Store
+	Checksummer *Checksummer
+	// Scopes are the scopes of Store, see [Checksummer.Sum].
+	Scopes []store.Scope
+	// PageSize is the number of objects listed per page, default to 100.
+	PageSize int
+}
+
+func NewScanner(s store.Store, options *Options) *Scanner {
+	return &Scanner{Store: s, Checksummer: NewChecksummer(options), PageSize: 100}
+}
+
+// Scan verifies all objects of the resource in the scope of the store and its sub scopes.
+func (s *Scanner) Scan(ctx context.Context, resource string) (*ScanReport, error) {
+	report := &ScanReport{Resource: resource}
+	err := s.each(ctx, resource, func(item store.Object) error {
+		report.Scanned++
+		signed, err := s.Checksummer.Verify(item, s.Scopes...)
+		switch {
+		case IsCorrupted(err):
+			report.Corrupted = append(report.Corrupted, store.ObjectReferenceFrom(item))
+		case err != nil:
+			return err
+		case !signed:
+			report.Unsigned = append(report.Unsigned, store.ObjectReferenceFrom(item))
+		}
+		return nil
+	}, store.WithSubScopes())
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// each calls fn on every object of the resource page by page.
+func (s *Scanner) each(ctx context.Context, resource string, fn func(item store.Object) error, opts ...store.ListOption) error {
+	size := s.PageSize
+	if size <= 0 {
+		size = 100
+	}
+	for page := 1; ; page++ {
+		list := &store.List[store.Unstructured]{}
+		list.SetResource(resource)
+		if err := s.Store.List(ctx, list, append(opts, store.WithPageSize(page, size), store.WithoutSizePolicy())...); err != nil {
+			return err
+		}
+		for i := range list.Items {
+			if err := fn(&list.Items[i]); err != nil {
+				return err
+			}
+		}
+		// Total is not maintained by every backend, the last page is shorter than size
+		if len(list.Items) < size {
+			return nil
+		}
+	}
+}
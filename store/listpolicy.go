@@ -0,0 +1,108 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/meta"
+)
+
+// ListPolicy is the list policy of a resource, applied by all backends on List,
+// so lists are ordered and bounded the same regardless of the backend.
+type ListPolicy struct {
+	// DefaultSort is the sort used when the list options has no sort, in the format of [ListOptions.Sort].
+	// Backends use their own order if it is empty.
+	DefaultSort string
+	// DefaultSize is the page size used when the list options has no size, MaxSize is used if it is 0.
+	DefaultSize int
+	// MaxSize is the maximum page size, lists with a larger size are rejected. 0 means no limit.
+	MaxSize int
+}
+
+var GlobalListPolicies = NewListPolicies()
+
+func NewListPolicies() *ListPolicies {
+	return &ListPolicies{policies: map[string]ListPolicy{}}
+}
+
+// ListPolicies is a registry of list policies by resource.
+type ListPolicies struct {
+	lock          sync.RWMutex
+	defaultPolicy ListPolicy
+	policies      map[string]ListPolicy
+}
+
+// Register sets the policy of the resource, it overrides the default policy.
+func (p *ListPolicies) Register(resource string, policy ListPolicy) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.policies[resource] = policy
+}
+
+// SetDefault sets the policy of resources without a registered policy.
+func (p *ListPolicies) SetDefault(policy ListPolicy) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.defaultPolicy = policy
+}
+
+// Get returns the policy of the resource.
+func (p *ListPolicies) Get(resource string) ListPolicy {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if policy, ok := p.policies[resource]; ok {
+		return policy
+	}
+	return p.defaultPolicy
+}
+
+// Apply sets the default sort and page size of the resource into options,
+// it returns a BadRequest error if the page size exceeds the maximum.
+// Sizes are not applied if [ListOptions.SkipSizePolicy] is set.
+//
+// The sort is normalized so backends order the same: fields without direction are ascending
+// and the "time" alias is replaced by "creationTimestamp", e.g. "time-,name" becomes "creationTimestamp-,name+".
+func (p *ListPolicies) Apply(resource string, options *ListOptions) error {
+	policy := p.Get(resource)
+	if options.Sort == "" {
+		options.Sort = policy.DefaultSort
+	}
+	options.Sort = normalizeSort(options.Sort)
+	if options.SkipSizePolicy {
+		return nil
+	}
+	if options.Size <= 0 {
+		options.Size = policy.DefaultSize
+		if options.Size <= 0 {
+			options.Size = policy.MaxSize
+		}
+	}
+	if policy.MaxSize > 0 && options.Size > policy.MaxSize {
+		return errors.NewBadRequest(fmt.Sprintf("page size %d of %s exceeds the maximum %d", options.Size, resource, policy.MaxSize))
+	}
+	return nil
+}
+
+func normalizeSort(sort string) string {
+	sorts := ParseSorts(sort)
+	fields := make([]string, 0, len(sorts))
+	for _, s := range sorts {
+		if s.Field == "time" {
+			s.Field = "creationTimestamp"
+		}
+		if s.Direction == meta.SortDirectionDesc {
+			fields = append(fields, s.Field+"-")
+		} else {
+			fields = append(fields, s.Field+"+")
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// ApplyListPolicy applies the policy of the resource in [GlobalListPolicies] into options,
+// backends call it before listing.
+func ApplyListPolicy(resource string, options *ListOptions) error {
+	return GlobalListPolicies.Apply(resource, options)
+}
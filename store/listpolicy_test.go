@@ -0,0 +1,81 @@
+package store
+
+import (
+	"net/http"
+	"testing"
+
+	"xiaoshiai.cn/common/errors"
+)
+
+func TestListPoliciesApply(t *testing.T) {
+	policies := NewListPolicies()
+	policies.SetDefault(ListPolicy{DefaultSort: "time-"})
+	policies.Register("users", ListPolicy{DefaultSort: "name", DefaultSize: 20, MaxSize: 100})
+	policies.Register("events", ListPolicy{MaxSize: 50})
+
+	tests := []struct {
+		name     string
+		resource string
+		options  ListOptions
+		want     ListOptions
+		wantErr  bool
+	}{
+		{
+			name:     "default policy",
+			resource: "tenants",
+			want:     ListOptions{Sort: "creationTimestamp-"},
+		},
+		{
+			name:     "defaults",
+			resource: "users",
+			want:     ListOptions{Sort: "name+", Size: 20},
+		},
+		{
+			name:     "requested",
+			resource: "users",
+			options:  ListOptions{Sort: "name-", Page: 2, Size: 100},
+			want:     ListOptions{Sort: "name-", Page: 2, Size: 100},
+		},
+		{
+			name:     "exceeds max size",
+			resource: "users",
+			options:  ListOptions{Size: 101},
+			wantErr:  true,
+		},
+		{
+			name:     "max size as default size",
+			resource: "events",
+			want:     ListOptions{Size: 50},
+		},
+		{
+			name:     "skip size policy",
+			resource: "users",
+			options:  ListOptions{Size: 1000, SkipSizePolicy: true},
+			want:     ListOptions{Sort: "name+", Size: 1000, SkipSizePolicy: true},
+		},
+		{
+			name:     "normalized sort",
+			resource: "events",
+			options:  ListOptions{Sort: "time, name- ,spec.value+", Size: 10},
+			want:     ListOptions{Sort: "creationTimestamp+,name-,spec.value+", Size: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			err := policies.Apply(tt.resource, &options)
+			if tt.wantErr {
+				if !errors.IsCode(err, http.StatusBadRequest) {
+					t.Errorf("Apply() error = %v, want bad request", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if options.Sort != tt.want.Sort || options.Page != tt.want.Page || options.Size != tt.want.Size {
+				t.Errorf("Apply() = %+v, want %+v", options, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxIndexNamespaceLength is the maximum length in bytes of an index namespace "<db>.<collection>.$<index>".
+// older MongoDB versions limit it to 127 bytes, index names are kept within it.
+const MaxIndexNamespaceLength = 127
+
+const indexNameHashLength = 12
+
+type IndexKind string
+
+const (
+	IndexKindUnique         IndexKind = "uniq"
+	IndexKindNullableUnique IndexKind = "nuniq"
+	IndexKindNormal         IndexKind = "idx"
+)
+
+// IndexName returns a deterministic, length-safe index name for the fields of the collection namespace "<db>.<collection>".
+// The name is a readable prefix of the kind and joined fields followed by a hash of the kind and all fields,
+// e.g. "uniq_name_tenant_3f2a1b0c9d8e", so different definitions sharing a prefix get different names.
+// The prefix is truncated to keep the index namespace within [MaxIndexNamespaceLength],
+// it is dropped if the collection namespace is too long, MongoDB rejects such an index anyway.
+func IndexName(namespace string, kind IndexKind, fields []string) string {
+	sum := sha256.Sum256([]byte(string(kind) + "\x00" + strings.Join(fields, "\x00")))
+	hash := hex.EncodeToString(sum[:])[:indexNameHashLength]
+
+	prefix := string(kind) + "_" + strings.Join(fields, "_")
+	if max := MaxIndexNamespaceLength - len(namespace) - len(".$") - indexNameHashLength - 1; len(prefix) > max {
+		if max <= 0 {
+			return hash
+		}
+		prefix = prefix[:max]
+	}
+	return prefix + "_" + hash
+}
+
+// IndexModels builds the index models of the defination for the collection namespace "<db>.<collection>",
+// scope keys are appended to every index so uniqueness is under scopes.
+func IndexModels(namespace string, defination ObjectDefination) []mongo.IndexModel {
+	uniques := defination.Uniques
+	if uniques == nil {
+		// default unique index is name
+		uniques = []UnionFields{{"id"}}
+	}
+	scopesKeys := defination.ScopeKeys
+
+	indexes := []mongo.IndexModel{}
+	names := map[string][]string{}
+	add := func(kind IndexKind, fields []string, options *mongooptions.IndexOptions) {
+		// copy to avoid modify the defination
+		fields = append(append([]string{}, fields...), scopesKeys...)
+		name := IndexName(namespace, kind, fields)
+		if exists, ok := names[name]; ok {
+			if slices.Equal(exists, fields) {
+				// duplicated defination
+				return
+			}
+			// different definations with the same name, resolve by suffix
+			for i := 1; ; i++ {
+				if _, ok := names[fmt.Sprintf("%s_%d", name, i)]; !ok {
+					name = fmt.Sprintf("%s_%d", name, i)
+					break
+				}
+			}
+		}
+		names[name] = fields
+		indexes = append(indexes, mongo.IndexModel{Keys: listToBsonD(fields), Options: options.SetName(name)})
+	}
+	for _, uniq := range uniques {
+		add(IndexKindUnique, uniq, mongooptions.Index().SetUnique(true))
+	}
+	for _, nulluniq := range defination.NullableUniques {
+		fields := append(append([]string{}, nulluniq...), scopesKeys...)
+		options := mongooptions.Index().SetUnique(true).SetPartialFilterExpression(PartialFilterExpression(fields))
+		add(IndexKindNullableUnique, nulluniq, options)
+	}
+	for _, index := range defination.Indexes {
+		add(IndexKindNormal, index, mongooptions.Index())
+	}
+	return indexes
+}
+
+// reconcileIndexes drops existing indexes with the legacy names of desired indexes, so that the desired indexes can be created.
+// MongoDB can not rename an index, and creating an index with existing keys but another name fails.
+func (m *MongoStorageCore) reconcileIndexes(ctx context.Context, col *mongo.Collection, desired []mongo.IndexModel) error {
+	specs, err := col.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rename := range legacyIndexes(specs, desired) {
+		m.logger.Info("rename index", "collection", col.Name(), "from", rename[0], "to", rename[1])
+		if _, err := col.Indexes().DropOne(ctx, rename[0]); err != nil {
+			return fmt.Errorf("drop index %s of %s: %w", rename[0], col.Name(), err)
+		}
+	}
+	return nil
+}
+
+// legacyIndexes returns pairs of legacy and desired names of existing indexes to be renamed.
+// Legacy indexes were named by joining their fields, e.g. "name_tenant", only indexes with such a name
+// and the keys of a desired index are renamed, indexes created by others are kept.
+func legacyIndexes(specs []*mongo.IndexSpecification, desired []mongo.IndexModel) [][2]string {
+	renames, renamed := [][2]string{}, map[string]bool{}
+	for _, model := range desired {
+		keys, ok := model.Keys.(bson.D)
+		if !ok {
+			continue
+		}
+		fields := make([]string, 0, len(keys))
+		for _, key := range keys {
+			fields = append(fields, key.Key)
+		}
+		legacy, name := strings.Join(fields, "_"), *model.Options.Name
+		if legacy == name || renamed[legacy] {
+			continue
+		}
+		keysdoc, err := bson.Marshal(keys)
+		if err != nil {
+			continue
+		}
+		for _, spec := range specs {
+			if spec.Name == legacy && bytes.Equal(spec.KeysDocument, keysdoc) {
+				renames = append(renames, [2]string{legacy, name})
+				renamed[legacy] = true
+			}
+		}
+	}
+	return renames
+}
@@ -0,0 +1,105 @@
+package mongo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestIndexName(t *testing.T) {
+	short := IndexName("db.tests", IndexKindUnique, []string{"name", "tenant"})
+	if !strings.HasPrefix(short, "uniq_name_tenant_") {
+		t.Errorf("IndexName() = %s, want readable prefix", short)
+	}
+	if again := IndexName("db.tests", IndexKindUnique, []string{"name", "tenant"}); again != short {
+		t.Errorf("IndexName() is not deterministic, %s != %s", short, again)
+	}
+	if other := IndexName("db.tests", IndexKindNormal, []string{"name", "tenant"}); other == short {
+		t.Errorf("IndexName() of different kinds collides: %s", other)
+	}
+
+	long := []string{}
+	for i := 0; i < 20; i++ {
+		long = append(long, "very-long-scope-key-of-a-deeply-nested-resource")
+	}
+	a := IndexName("db.tests", IndexKindUnique, append(long, "a"))
+	b := IndexName("db.tests", IndexKindUnique, append(long, "b"))
+	if len("db.tests.$"+a) > MaxIndexNamespaceLength || len("db.tests.$"+b) > MaxIndexNamespaceLength {
+		t.Errorf("IndexName() exceeds %d bytes: %d, %d", MaxIndexNamespaceLength, len(a), len(b))
+	}
+	if a == b {
+		t.Errorf("IndexName() of definitions sharing a prefix collides: %s", a)
+	}
+
+	// the collection name counts towards the limit
+	namespace := "database." + strings.Repeat("c", 80)
+	name := IndexName(namespace, IndexKindUnique, []string{"name", "tenant", "organization", "workspace"})
+	if full := namespace + ".$" + name; len(full) > MaxIndexNamespaceLength {
+		t.Errorf("index namespace %s exceeds %d bytes: %d", full, MaxIndexNamespaceLength, len(full))
+	}
+	if !strings.HasPrefix(name, "uniq_name_") {
+		t.Errorf("IndexName() = %s, want readable prefix", name)
+	}
+	if name == IndexName(namespace, IndexKindUnique, []string{"name", "tenant", "organization", "project"}) {
+		t.Errorf("IndexName() of definitions sharing a prefix collides: %s", name)
+	}
+	if name := IndexName(strings.Repeat("c", 200), IndexKindUnique, []string{"name"}); len(name) != indexNameHashLength {
+		t.Errorf("IndexName() of a too long namespace = %s, want the hash only", name)
+	}
+}
+
+func TestIndexModels(t *testing.T) {
+	models := IndexModels("db.tests", ObjectDefination{
+		Uniques:   []UnionFields{{"name"}, {"name"}},
+		Indexes:   []UnionFields{{"status.phase"}},
+		ScopeKeys: []string{"tenant"},
+	})
+	if len(models) != 2 {
+		t.Fatalf("IndexModels() returns %d models, want 2", len(models))
+	}
+	for _, model := range models {
+		if name := *model.Options.Name; !strings.Contains(name, "_tenant_") {
+			t.Errorf("index %s is not scoped", name)
+		}
+	}
+	if !*models[0].Options.Unique {
+		t.Errorf("unique index is not unique")
+	}
+}
+
+func TestLegacyIndexes(t *testing.T) {
+	desired := IndexModels("db.tests", ObjectDefination{
+		Uniques:   []UnionFields{{"name"}},
+		Indexes:   []UnionFields{{"name"}, {"status.phase"}},
+		ScopeKeys: []string{"tenant"},
+	})
+	keys := func(fields ...string) bson.Raw {
+		raw, err := bson.Marshal(listToBsonD(fields))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	specs := []*mongo.IndexSpecification{
+		{Name: "_id_", KeysDocument: keys("_id")},
+		// legacy names
+		{Name: "name_tenant", KeysDocument: keys("name", "tenant")},
+		{Name: "status.phase_tenant", KeysDocument: keys("status.phase", "tenant")},
+		// desired name
+		{Name: *desired[0].Options.Name, KeysDocument: keys("name", "tenant")},
+		// created by others with the same keys
+		{Name: "phase_by_tenant", KeysDocument: keys("status.phase", "tenant")},
+		// legacy name with other keys
+		{Name: "status.phase_tenant", KeysDocument: keys("tenant", "status.phase")},
+	}
+	want := [][2]string{
+		{"name_tenant", *desired[0].Options.Name},
+		{"status.phase_tenant", *desired[2].Options.Name},
+	}
+	if got := legacyIndexes(specs, desired); !reflect.DeepEqual(got, want) {
+		t.Errorf("legacyIndexes() = %v, want %v", got, want)
+	}
+}
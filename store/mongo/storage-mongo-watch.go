@@ -35,6 +35,7 @@ func (m *MongoStorage) Watch(ctx context.Context, obj store.ObjectList, opts ...
 	}
 	var watcher store.Watcher
 	err = m.on(ctx, obj, func(ctx context.Context, col *mongo.Collection, filter bson.D) error {
+		filter = m.subscopesmatch(filter, col.Name(), options.IncludeSubScopes)
 		filter = conditionsmatch(filter, SelectorToReqirements(options.LabelRequirements, options.FieldRequirements))
 		if options.ID != "" {
 			filter = append(filter, bson.E{Key: "id", Value: options.ID})
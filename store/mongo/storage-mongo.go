@@ -178,6 +178,21 @@ func NewMongoDB(ctx context.Context,
 var setUpdateTimestampQuery = bson.E{Key: "$currentDate", Value: bson.D{{Key: "updationTimestamp", Value: true}}}
 var incGenerationQuery = bson.E{Key: "$inc", Value: bson.D{{Key: "generation", Value: 1}}}
 
+// setUpdatedByQuery adds setting updatedBy to the identity of the context into the "$set" of update.
+func setUpdatedByQuery(ctx context.Context, update bson.D) bson.D {
+	identity := store.IdentityFromContext(ctx)
+	if identity == "" {
+		return update
+	}
+	for i, e := range update {
+		if set, ok := e.Value.(bson.D); ok && e.Key == "$set" {
+			update[i].Value = append(set, bson.E{Key: "updatedBy", Value: identity})
+			return update
+		}
+	}
+	return append(update, bson.E{Key: "$set", Value: bson.D{{Key: "updatedBy", Value: identity}}})
+}
+
 type MongoStorageCore struct {
 	scheme             *ObjectScheme
 	db                 *mongo.Database
@@ -195,44 +210,10 @@ func (m *MongoStorageCore) initCollections(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		if defination.Uniques == nil {
-			// default unique index is name
-			defination.Uniques = []UnionFields{{"id"}}
-		}
 		col := m.db.Collection(resource)
-		indexes := []mongo.IndexModel{}
-		// scopes keys
-		scopesKeys := defination.ScopeKeys
-		// unique indexes
-		for _, uniq := range defination.Uniques {
-			// unique index is under scopes
-			uniq = append(uniq, scopesKeys...)
-			indexes = append(indexes, mongo.IndexModel{
-				Keys:    listToBsonD(uniq),
-				Options: mongooptions.Index().SetName(strings.Join(uniq, "_")).SetUnique(true),
-			})
-		}
-		// partial indexes
-		for _, nulluniq := range defination.NullableUniques {
-			// unique index is under scopes
-			nulluniq = append(nulluniq, scopesKeys...)
-			indexes = append(indexes, mongo.IndexModel{
-				Keys: listToBsonD(nulluniq),
-				Options: mongooptions.
-					Index().
-					SetName(strings.Join(nulluniq, "_")).
-					SetUnique(true).
-					SetPartialFilterExpression(PartialFilterExpression(nulluniq)),
-			})
-		}
-		// normal indexes
-		for _, index := range defination.Indexes {
-			// indexes is under scopes
-			index = append(index, scopesKeys...)
-			indexes = append(indexes, mongo.IndexModel{
-				Keys:    listToBsonD(index),
-				Options: mongooptions.Index().SetName(strings.Join(index, "_")),
-			})
+		indexes := IndexModels(m.db.Name()+"."+col.Name(), defination)
+		if err := m.reconcileIndexes(ctx, col, indexes); err != nil {
+			return err
 		}
 		m.logger.V(5).Info("init indexes", "collection", col.Name(), "indexes", indexes)
 		if _, err := col.Indexes().CreateMany(ctx, indexes); err != nil {
@@ -293,6 +274,7 @@ func (m *MongoStorage) Count(ctx context.Context, obj store.Object, opts ...stor
 	}
 	var count int
 	err := m.on(ctx, obj, func(ctx context.Context, col *mongo.Collection, filter bson.D) error {
+		filter = m.subscopesmatch(filter, col.Name(), options.IncludeSubScopes)
 		filter = conditionsmatch(filter, SelectorToReqirements(options.LabelRequirements, options.FieldRequirements))
 		m.core.logger.V(5).Info("count", "collection", col.Name(), "filter", filter)
 		doccount, err := col.CountDocuments(ctx, filter)
@@ -327,6 +309,7 @@ func (m *MongoStorage) Create(ctx context.Context, into store.Object, opts ...st
 		into.SetCreationTimestamp(meta.Now())
 		into.SetUID(uuid.NewString())
 		into.SetGeneration(1)
+		store.SetCreatedBy(ctx, into)
 		data, err := m.mergeConditionOnChange(into, []string{"status"})
 		if err != nil {
 			return err
@@ -413,12 +396,20 @@ func (m *MongoStorage) DeleteBatch(ctx context.Context, obj store.ObjectList, op
 	for _, opt := range opts {
 		opt(&options)
 	}
+	if err := store.ValidateBatchRequirements(options.LabelRequirements, options.FieldRequirements, options.AllowUnfiltered); err != nil {
+		return err
+	}
 	return m.on(ctx, obj, func(ctx context.Context, col *mongo.Collection, filter bson.D) error {
 		filter = conditionsmatch(filter, SelectorToReqirements(options.LabelRequirements, options.FieldRequirements))
+		if err := m.checkBatchAffected(ctx, col, filter, options.MaxAffected); err != nil {
+			return err
+		}
 		m.core.logger.V(5).Info("delete all", "collection", col.Name(), "filter", filter)
-		if _, err := col.DeleteMany(ctx, filter); err != nil {
+		result, err := col.DeleteMany(ctx, filter)
+		if err != nil {
 			return WarpMongoError(err, col, nil)
 		}
+		obj.SetTotal(int(result.DeletedCount))
 		return nil
 	})
 }
@@ -465,7 +456,13 @@ func (m *MongoStorage) Update(ctx context.Context, obj store.Object, opts ...sto
 		filter = append(filter, bson.E{Key: "id", Value: id})
 		filter = conditionsmatch(filter, SelectorToReqirements(updateoptions.LabelRequirements, updateoptions.FieldRequirements))
 		// in order not to update creation time or creator
-		fields, err := m.mergeConditionOnChange(obj, []string{"creator", "creationTimestamp", "status", "generation"})
+		excludes := []string{"creator", "createdBy", "creationTimestamp", "status", "generation"}
+		if store.IdentityFromContext(ctx) == "" {
+			// keep the stored updatedBy, values sent by clients are ignored
+			excludes = append(excludes, "updatedBy")
+		}
+		store.SetUpdatedBy(ctx, obj, "")
+		fields, err := m.mergeConditionOnChange(obj, excludes)
 		if err != nil {
 			return err
 		}
@@ -498,10 +495,11 @@ func (m *MongoStorage) Patch(ctx context.Context, obj store.Object, patch store.
 	return m.on(ctx, obj, func(ctx context.Context, col *mongo.Collection, filter bson.D) error {
 		filter = append(filter, bson.E{Key: "id", Value: id})
 		filter = conditionsmatch(filter, SelectorToReqirements(options.LabelRequirements, options.FieldRequirements))
-		update, err := convertPatch(patch, obj, []string{"creator", "creationTimestamp", "status", "generation"}, nil)
+		update, err := convertPatch(patch, obj, []string{"creator", "createdBy", "updatedBy", "creationTimestamp", "status", "generation"}, nil)
 		if err != nil {
 			return err
 		}
+		update = setUpdatedByQuery(ctx, update)
 		update = append(update, incGenerationQuery)
 		if m.core.setUpdateTimestamp {
 			update = append(update, setUpdateTimestampQuery)
@@ -521,32 +519,61 @@ func (m *MongoStorage) PatchBatch(ctx context.Context, obj store.ObjectList, pat
 	for _, opt := range opts {
 		opt(&options)
 	}
+	if err := store.ValidateBatchRequirements(options.LabelRequirements, options.FieldRequirements, options.AllowUnfiltered); err != nil {
+		return err
+	}
 	return m.on(ctx, obj, func(ctx context.Context, col *mongo.Collection, filter bson.D) error {
 		filter = conditionsmatch(filter, SelectorToReqirements(options.LabelRequirements, options.FieldRequirements))
-		update, err := convertBatchPatch(patch, []string{"creator", "creationTimestamp", "status", "generation"}, nil)
+		update, err := convertBatchPatch(patch, []string{"creator", "createdBy", "updatedBy", "creationTimestamp", "status", "generation"}, nil)
 		if err != nil {
 			return err
 		}
+		update = setUpdatedByQuery(ctx, update)
+		if err := m.checkBatchAffected(ctx, col, filter, options.MaxAffected); err != nil {
+			return err
+		}
 		m.core.logger.V(5).Info("batch patch", "collection", col.Name(), "filter", filter, "update", update)
-		if _, err := col.UpdateMany(ctx, filter, update); err != nil {
+		result, err := col.UpdateMany(ctx, filter, update)
+		if err != nil {
 			return ConvetMongoListError(err, col)
 		}
+		obj.SetTotal(int(result.MatchedCount))
 		return nil
 	})
 }
 
+// checkBatchAffected counts documents matching filter before a batch operation if max is set.
+func (m *MongoStorage) checkBatchAffected(ctx context.Context, col *mongo.Collection, filter bson.D, max int) error {
+	if max <= 0 {
+		return nil
+	}
+	count, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		return ConvetMongoListError(err, col)
+	}
+	return store.ValidateBatchAffected(int(count), max)
+}
+
 // List implements Storage.
 func (m *MongoStorage) List(ctx context.Context, list store.ObjectList, opts ...store.ListOption) error {
 	options := store.ListOptions{}
 	for _, opt := range opts {
 		opt(&options)
 	}
+	resource, err := store.GetResource(list)
+	if err != nil {
+		return err
+	}
+	if err := store.ApplyListPolicy(resource, &options); err != nil {
+		return err
+	}
 	list.SetPage(options.Page)
 	list.SetSize(options.Size)
 
 	// if projection is empty, set projection from list object
 	// currently, we don't use this feature
 	return m.on(ctx, list, func(ctx context.Context, col *mongo.Collection, filter bson.D) error {
+		filter = m.subscopesmatch(filter, col.Name(), options.IncludeSubScopes)
 		pipeline := listPipeline(filter, nil, options, options.Fields, nil)
 		m.core.logger.V(5).Info("list", "collection", col.Name(), "pipeline", pipeline)
 		cur, err := col.Aggregate(ctx, pipeline)
@@ -665,6 +692,38 @@ func scopesmatch(match bson.D, scopes []store.Scope) bson.D {
 	return conditionsmatch(match, conds)
 }
 
+// subscopesmatch restricts match to objects directly under the scopes of the store unless includeSubScopes,
+// for resources defined with [ObjectDefination.ExcludeSubScopes].
+func (m *MongoStorage) subscopesmatch(match bson.D, resource string, includeSubScopes bool) bson.D {
+	defination, err := m.core.scheme.GetDefination(resource)
+	if err != nil || !defination.ExcludeSubScopes {
+		// objects in sub scopes match by default
+		return match
+	}
+	return subscopesmatch(match, defination.ScopeKeys, m.scopes, includeSubScopes)
+}
+
+// subscopesmatch matches the scope chain of an object stored as scope fields, e.g. {"tenant": "t1", "project": "p1"}.
+// scopesmatch already requires the fields of scopes to be equal, so the chain of scopes is a prefix of the object's.
+// Without includeSubScopes, scope keys deeper than scopes must be absent, so only objects directly under scopes match.
+// Sub scoped objects can be told only by scope keys of the resource [ObjectDefination.ScopeKeys],
+// resources without scope keys always match sub scoped objects.
+func subscopesmatch(match bson.D, scopeKeys []string, scopes []store.Scope, includeSubScopes bool) bson.D {
+	if includeSubScopes {
+		return match
+	}
+	for _, key := range scopeKeys {
+		if slices.ContainsFunc(scopes, func(scope store.Scope) bool {
+			return store.ScopeResourceToFieldName(scope.Resource) == key
+		}) {
+			continue
+		}
+		// matches both null and missing fields
+		match = append(match, bson.E{Key: key, Value: nil})
+	}
+	return match
+}
+
 func conditionsmatch(match bson.D, conds store.Requirements) bson.D {
 	for _, cond := range conds {
 		key, values := cond.Key, cond.Values
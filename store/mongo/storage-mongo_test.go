@@ -1,6 +1,7 @@
 package mongo
 
 import (
+	"cmp"
 	"reflect"
 	"testing"
 
@@ -60,3 +61,72 @@ func TestMergePatchToBsonUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestSubscopesmatch(t *testing.T) {
+	scopeKeys := []string{"tenant", "project"}
+	tenant := []store.Scope{{Resource: "tenants", Name: "t1"}}
+	tests := []struct {
+		name             string
+		scopes           []store.Scope
+		includeSubScopes bool
+		want             bson.D
+	}{
+		{
+			name:   "exact scope",
+			scopes: tenant,
+			want:   bson.D{{Key: "tenant", Value: "t1"}, {Key: "project", Value: nil}},
+		},
+		{
+			name:             "include sub scopes",
+			scopes:           tenant,
+			includeSubScopes: true,
+			want:             bson.D{{Key: "tenant", Value: "t1"}},
+		},
+		{
+			name: "root",
+			want: bson.D{{Key: "tenant", Value: nil}, {Key: "project", Value: nil}},
+		},
+		{
+			name:   "deepest scope",
+			scopes: append(tenant, store.Scope{Resource: "projects", Name: "p1"}),
+			want:   bson.D{{Key: "tenant", Value: "t1"}, {Key: "project", Value: "p1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := subscopesmatch(scopesmatch(bson.D{}, tt.scopes), scopeKeys, tt.scopes, tt.includeSubScopes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subscopesmatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMongoStorageSubscopesmatch(t *testing.T) {
+	tenant := store.Scope{Resource: "tenants", Name: "t1"}
+	for _, tt := range []struct {
+		name     string
+		exclude  bool
+		resource string
+		want     bson.D
+	}{
+		{name: "sub scopes match by default", want: bson.D{}},
+		{name: "exclude sub scopes", exclude: true, want: bson.D{{Key: "project", Value: nil}}},
+		{name: "unregistered resource matches sub scopes", resource: "others", want: bson.D{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := NewObjectScheme()
+			if err := scheme.Register(&TestObject{}, ObjectDefination{ScopeKeys: []string{"tenant", "project"}, ExcludeSubScopes: tt.exclude}); err != nil {
+				t.Fatal(err)
+			}
+			resource := cmp.Or(tt.resource, "testobjects")
+			m := &MongoStorage{core: &MongoStorageCore{scheme: scheme}, scopes: []store.Scope{tenant}}
+			if got := m.subscopesmatch(bson.D{}, resource, false); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subscopesmatch() = %v, want %v", got, tt.want)
+			}
+			if got := m.subscopesmatch(bson.D{}, resource, true); len(got) != 0 {
+				t.Errorf("subscopesmatch() including sub scopes = %v, want no condition", got)
+			}
+		})
+	}
+}
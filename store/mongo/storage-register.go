@@ -19,8 +19,15 @@ type ObjectDefination struct {
 	// NullableUniques are unique fields that can be null
 	NullableUniques []UnionFields
 	Indexes         []UnionFields
-	ScopeKeys       []string
-	Schema          *spec.Schema
+	// ScopeKeys are the scope fields of the full scope chain of the resource, e.g. ["tenant", "project"].
+	// They are appended to indexes.
+	ScopeKeys []string
+	// ExcludeSubScopes makes List, Count and Watch match only objects directly under the scopes of the store
+	// unless IncludeSubScopes is set, like the etcd stores. Objects in sub scopes are told by ScopeKeys.
+	// It is opt-in per resource: by default IncludeSubScopes is ignored and objects in sub scopes
+	// are always matched, as existing callers of the mongo store expect.
+	ExcludeSubScopes bool
+	Schema           *spec.Schema
 }
 
 var GlobalObjectsScheme = NewObjectScheme()
@@ -45,6 +45,14 @@ type Object interface {
 
 	GetOwnerReferences() []OwnerReference
 	SetOwnerReferences([]OwnerReference)
+
+	// GetCreatedBy, SetCreatedBy, GetUpdatedBy and SetUpdatedBy are required since identity tracking,
+	// see [WithIdentity]. It is a breaking change for implementations not embedding [ObjectMeta] or [Unstructured].
+	GetCreatedBy() string
+	SetCreatedBy(string)
+
+	GetUpdatedBy() string
+	SetUpdatedBy(string)
 }
 
 type ObjectList interface {
@@ -103,6 +111,10 @@ type ObjectMeta struct {
 	Finalizers        []string          `json:"finalizers,omitempty"`
 	OwnerReferences   []OwnerReference  `json:"ownerReferences,omitempty"`
 	Description       string            `json:"description,omitempty"`
+	// CreatedBy is the identity created the object, it is set by the store from [IdentityFromContext] on creation.
+	CreatedBy string `json:"createdBy,omitempty"`
+	// UpdatedBy is the identity last updated the object, it is set by the store from [IdentityFromContext] on update.
+	UpdatedBy string `json:"updatedBy,omitempty"`
 }
 
 func (o *ObjectMeta) GetID() string {
@@ -253,6 +265,26 @@ func (o *ObjectMeta) SetUID(uid string) {
 	o.UID = uid
 }
 
+// GetCreatedBy implements Object.
+func (o *ObjectMeta) GetCreatedBy() string {
+	return o.CreatedBy
+}
+
+// SetCreatedBy implements Object.
+func (o *ObjectMeta) SetCreatedBy(createdBy string) {
+	o.CreatedBy = createdBy
+}
+
+// GetUpdatedBy implements Object.
+func (o *ObjectMeta) GetUpdatedBy() string {
+	return o.UpdatedBy
+}
+
+// SetUpdatedBy implements Object.
+func (o *ObjectMeta) SetUpdatedBy(updatedBy string) {
+	o.UpdatedBy = updatedBy
+}
+
 var _ ObjectList = &List[Object]{}
 
 type List[T any] struct {
@@ -61,7 +61,9 @@ func (c *Client) PatchBatch(ctx context.Context, obj store.ObjectList, patch sto
 	if len(options.FieldRequirements) != 0 {
 		queries.Add("fieldSelector", options.FieldRequirements.String())
 	}
+	setBatchSafetyQueries(queries, options.AllowUnfiltered, options.MaxAffected)
 	return c.cli.Patch(c.getPath(resource, "")).
+		Queries(queries).
 		Query("batch", "true").
 		Query("status", strconv.FormatBool(false)).
 		Body(bytes.NewReader(patchdata), string(patchtype)).
@@ -85,9 +87,19 @@ func (c *Client) DeleteBatch(ctx context.Context, obj store.ObjectList, opts ...
 	if len(options.FieldRequirements) != 0 {
 		queries.Add("fieldSelector", options.FieldRequirements.String())
 	}
+	setBatchSafetyQueries(queries, options.AllowUnfiltered, options.MaxAffected)
 	return c.cli.Delete(c.getPath(resource, "")).Queries(queries).Return(obj).Send(ctx)
 }
 
+func setBatchSafetyQueries(queries url.Values, allowUnfiltered bool, maxAffected int) {
+	if allowUnfiltered {
+		queries.Set("allowUnfiltered", "true")
+	}
+	if maxAffected > 0 {
+		queries.Set("maxAffected", strconv.Itoa(maxAffected))
+	}
+}
+
 // Count implements store.Store.
 func (c Client) Count(ctx context.Context, obj store.Object, opts ...store.CountOption) (int, error) {
 	resource, err := store.GetResource(obj)
@@ -171,6 +183,8 @@ func (c Client) Get(ctx context.Context, name string, obj store.Object, opts ...
 }
 
 // List implements store.Store.
+// [store.ListOptions.SkipSizePolicy] is not sent, the server never skips the size policy for requests,
+// so lists are bounded by the policy of the server.
 func (c Client) List(ctx context.Context, list store.ObjectList, opts ...store.ListOption) error {
 	resource, err := store.GetResource(list)
 	if err != nil {
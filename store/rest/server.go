@@ -34,6 +34,7 @@ func (s *Server) List(w http.ResponseWriter, r *http.Request) {
 	s.on(w, r, func(ctx context.Context, ref store.ResourcedObjectReference) (any, error) {
 		log := log.FromContext(ctx)
 		if ref.ID == "" {
+			// SkipSizePolicy is never read from requests, remote lists are bounded by the list policy
 			options := store.ListOptions{
 				Page:             api.Query(r, "page", 0),
 				Size:             api.Query(r, "size", 0),
@@ -187,6 +188,8 @@ func (s *Server) Patch(w http.ResponseWriter, r *http.Request) {
 			options := store.PatchBatchOptions{
 				LabelRequirements: labelsel,
 				FieldRequirements: fildsel,
+				AllowUnfiltered:   api.Query(r, "allowUnfiltered", false),
+				MaxAffected:       api.Query(r, "maxAffected", 0),
 			}
 			opts := []store.PatchBatchOption{
 				func(bpo *store.PatchBatchOptions) {
@@ -280,6 +283,8 @@ func (s *Server) Delete(w http.ResponseWriter, r *http.Request) {
 			options := store.DeleteBatchOptions{
 				LabelRequirements: labelsel,
 				FieldRequirements: fildsel,
+				AllowUnfiltered:   api.Query(r, "allowUnfiltered", false),
+				MaxAffected:       api.Query(r, "maxAffected", 0),
 			}
 			list := store.List[store.Unstructured]{}
 			list.Resource = ref.Resource
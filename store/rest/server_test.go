@@ -1,9 +1,15 @@
 package rest
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"testing"
 
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/rest/api"
 	"xiaoshiai.cn/common/store"
 )
 
@@ -59,3 +65,95 @@ func Test_decodePath(t *testing.T) {
 		})
 	}
 }
+
+// batchStore records the options of batch operations received by the server.
+type batchStore struct {
+	store.Store
+	scopes       []store.Scope
+	deleteOption store.DeleteBatchOptions
+	patchOption  store.PatchBatchOptions
+}
+
+func (s *batchStore) Scope(scope ...store.Scope) store.Store {
+	s.scopes = scope
+	return s
+}
+
+func (s *batchStore) DeleteBatch(ctx context.Context, list store.ObjectList, opts ...store.DeleteBatchOption) error {
+	for _, opt := range opts {
+		opt(&s.deleteOption)
+	}
+	if err := store.ValidateBatchRequirements(s.deleteOption.LabelRequirements, s.deleteOption.FieldRequirements, s.deleteOption.AllowUnfiltered); err != nil {
+		return err
+	}
+	if err := store.ValidateBatchAffected(3, s.deleteOption.MaxAffected); err != nil {
+		return err
+	}
+	list.SetTotal(3)
+	return nil
+}
+
+func (s *batchStore) PatchBatch(ctx context.Context, list store.ObjectList, patch store.PatchBatch, opts ...store.PatchBatchOption) error {
+	for _, opt := range opts {
+		opt(&s.patchOption)
+	}
+	list.SetTotal(2)
+	return nil
+}
+
+func TestBatchOptionsRoundTrip(t *testing.T) {
+	backend := &batchStore{}
+	server := httptest.NewServer(api.New().Group(NewServer(backend).Group()).Build())
+	defer server.Close()
+	serverurl, _ := url.Parse(server.URL)
+	client := NewRemoteStore(serverurl).Scope(store.Scope{Resource: "tenants", Name: "t1"})
+	ctx := context.Background()
+
+	labels := store.Requirements{store.RequirementEqual("app", "web")}
+	fields := store.Requirements{store.NewRequirement("status.phase", store.NotEquals, "Running")}
+
+	list := &store.List[store.Unstructured]{Resource: "pods"}
+	if err := client.DeleteBatch(ctx, list, store.WithDeleteBatchLabelRequirements(labels...), store.WithDeleteBatchFieldRequirements(fields...), store.WithDeleteBatchMaxAffected(5)); err != nil {
+		t.Fatalf("DeleteBatch() error = %v", err)
+	}
+	got := backend.deleteOption
+	if got.AllowUnfiltered || got.MaxAffected != 5 || got.LabelRequirements.String() != labels.String() || got.FieldRequirements.String() != fields.String() {
+		t.Errorf("server received delete options %+v", got)
+	}
+	if list.Total != 3 {
+		t.Errorf("DeleteBatch() total = %d, want 3", list.Total)
+	}
+	if !reflect.DeepEqual(backend.scopes, []store.Scope{{Resource: "tenants", Name: "t1"}}) {
+		t.Errorf("server received scopes %v", backend.scopes)
+	}
+
+	backend.deleteOption = store.DeleteBatchOptions{}
+	if err := client.DeleteBatch(ctx, &store.List[store.Unstructured]{Resource: "pods"}, store.WithDeleteBatchAllowUnfiltered()); err != nil {
+		t.Fatalf("DeleteBatch() unfiltered error = %v", err)
+	}
+	if got := backend.deleteOption; !got.AllowUnfiltered || got.MaxAffected != 0 || got.LabelRequirements != nil || got.FieldRequirements != nil {
+		t.Errorf("server received delete options %+v", got)
+	}
+
+	// errors of the backend are returned to the client
+	backend.deleteOption = store.DeleteBatchOptions{}
+	if err := client.DeleteBatch(ctx, &store.List[store.Unstructured]{Resource: "pods"}); !errors.IsCode(err, http.StatusBadRequest) {
+		t.Errorf("DeleteBatch() unfiltered error = %v, want bad request", err)
+	}
+	backend.deleteOption = store.DeleteBatchOptions{}
+	if err := client.DeleteBatch(ctx, &store.List[store.Unstructured]{Resource: "pods"}, store.WithDeleteBatchAllowUnfiltered(), store.WithDeleteBatchMaxAffected(2)); !errors.IsCode(err, http.StatusBadRequest) {
+		t.Errorf("DeleteBatch() exceeding max affected error = %v, want bad request", err)
+	}
+
+	list = &store.List[store.Unstructured]{Resource: "pods"}
+	patch := store.RawPatchBatch(store.PatchTypeMergePatch, []byte(`{"labels":{"app":"api"}}`))
+	if err := client.PatchBatch(ctx, list, patch, store.WithPatchBatchLabelRequirements(labels...), store.WithPatchBatchAllowUnfiltered(), store.WithPatchBatchMaxAffected(7)); err != nil {
+		t.Fatalf("PatchBatch() error = %v", err)
+	}
+	if got := backend.patchOption; !got.AllowUnfiltered || got.MaxAffected != 7 || got.LabelRequirements.String() != labels.String() || got.FieldRequirements != nil {
+		t.Errorf("server received patch options %+v", got)
+	}
+	if list.Total != 2 {
+		t.Errorf("PatchBatch() total = %d, want 2", list.Total)
+	}
+}
@@ -47,6 +47,16 @@ func NewCreationRangeRequirement(start, end time.Time) []Requirement {
 	return ret
 }
 
+// NewCreatedByRequirement matches objects created by any of the identities.
+func NewCreatedByRequirement(identities ...string) Requirement {
+	return NewRequirement("createdBy", In, StringsToAny(identities)...)
+}
+
+// NewUpdatedByRequirement matches objects last updated by any of the identities.
+func NewUpdatedByRequirement(identities ...string) Requirement {
+	return NewRequirement("updatedBy", In, StringsToAny(identities)...)
+}
+
 type Operator string
 
 const (
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/go-sql-driver/mysql"
@@ -268,6 +269,8 @@ type core struct {
 	db     *gorm.DB
 	helper *StructHelper
 	driver string
+	// columns caches whether a "table.column" exists
+	columns sync.Map
 }
 
 func (c *core) get(ctx context.Context, scope []store.Scope, id string, into store.Object, options store.GetOptions) error {
@@ -332,7 +335,9 @@ func (c *core) create(ctx context.Context, scopes []store.Scope, in store.Object
 		id = uuid.New().String()
 	}
 	in.SetCreationTimestamp(meta.Now())
+	store.SetCreatedBy(ctx, in)
 	save := c.helper.ToDriverValueMap(in)
+	c.dropMissingIdentityColumns(ctx, resource, save)
 	for _, cond := range scopes {
 		save[cond.Resource] = cond.Name
 	}
@@ -359,10 +364,18 @@ func (c *core) update(ctx context.Context, scope []store.Scope, into store.Objec
 	if id == "" {
 		return NewEmptyIDStorageError(resource)
 	}
+	if !status {
+		store.SetUpdatedBy(ctx, into, "")
+	}
 	save := c.helper.ToDriverValueMap(into)
 	maps.DeleteFunc(save, func(key string, _ any) bool {
-		return status && !slices.Contains(statusAllowedKeys, key) || !status && key == "status"
+		return status && !slices.Contains(statusAllowedKeys, key) || !status && (key == "status" || key == "createdBy")
 	})
+	if store.IdentityFromContext(ctx) == "" {
+		// keep the stored updatedBy, values sent by clients are ignored
+		delete(save, "updatedBy")
+	}
+	c.dropMissingIdentityColumns(ctx, resource, save)
 	for _, cond := range scope {
 		save[cond.Resource] = cond.Name
 	}
@@ -416,8 +429,11 @@ func (c *core) patch(ctx context.Context, scope []store.Scope, into store.Object
 		update = patchmap
 	}
 	maps.DeleteFunc(update, func(key string, _ any) bool {
-		return status && !slices.Contains(statusAllowedKeys, key) || !status && key == "status"
+		return status && !slices.Contains(statusAllowedKeys, key) || !status && slices.Contains([]string{"status", "createdBy", "updatedBy"}, key)
 	})
+	if identity := store.IdentityFromContext(ctx); identity != "" && !status && c.hasColumn(ctx, resource, "updatedBy") {
+		update["updatedBy"] = identity
+	}
 	db := c.prepare(ctx, resource, scope).Where("id = ?", id)
 	if options.FieldRequirements != nil {
 		db = c.applyFields(db, options.FieldRequirements)
@@ -535,20 +551,35 @@ func (c *core) deleteBatch(ctx context.Context, scope []store.Scope, list store.
 	if err != nil {
 		return err
 	}
-	items, err := store.GetItemsPtr(list)
-	if err != nil {
+	if err := store.ValidateBatchRequirements(options.LabelRequirements, options.FieldRequirements, options.AllowUnfiltered); err != nil {
 		return err
 	}
 	db := c.prepare(ctx, resource, scope)
+	if options.AllowUnfiltered {
+		// gorm refuses deleting without conditions unless allowed
+		db = db.Session(&gorm.Session{AllowGlobalUpdate: true})
+	}
 	if options.FieldRequirements != nil {
 		db = c.applyFields(db, options.FieldRequirements)
 	}
 	if options.LabelRequirements != nil {
 		db = c.applyLabels(db, options.LabelRequirements)
 	}
-	if err := db.Delete(items).Error; err != nil {
+	if options.MaxAffected > 0 {
+		var count int64
+		if err := db.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+			return mapSQLError(err, resource, "")
+		}
+		if err := store.ValidateBatchAffected(int(count), options.MaxAffected); err != nil {
+			return err
+		}
+	}
+	// items are not gorm models, delete from the table like delete does
+	result := db.Delete(map[string]any{})
+	if err := result.Error; err != nil {
 		return mapSQLError(err, resource, "")
 	}
+	list.SetTotal(int(result.RowsAffected))
 	return nil
 }
 
@@ -561,6 +592,9 @@ func (c *core) list(ctx context.Context, scope []store.Scope, list store.ObjectL
 	if err != nil {
 		return fmt.Errorf("get items pointer from list: %w", err)
 	}
+	if err := store.ApplyListPolicy(resource, &opts); err != nil {
+		return err
+	}
 
 	db := c.prepare(ctx, resource, scope)
 	if opts.Search != "" {
@@ -666,6 +700,28 @@ func (c *core) applyCondition(db *gorm.DB, key string, op store.Operator, vals [
 	}
 }
 
+var identityColumns = []string{"createdBy", "updatedBy"}
+
+// dropMissingIdentityColumns removes createdBy and updatedBy from save if the table has no such columns,
+// tables created before identity tracking keep working without migration.
+func (c *core) dropMissingIdentityColumns(ctx context.Context, table string, save map[string]any) {
+	for _, column := range identityColumns {
+		if _, ok := save[column]; ok && !c.hasColumn(ctx, table, column) {
+			delete(save, column)
+		}
+	}
+}
+
+func (c *core) hasColumn(ctx context.Context, table, column string) bool {
+	key := table + "." + column
+	if has, ok := c.columns.Load(key); ok {
+		return has.(bool)
+	}
+	has := c.db.WithContext(ctx).Migrator().HasColumn(table, column)
+	c.columns.Store(key, has)
+	return has
+}
+
 func (c *core) prepare(ctx context.Context, tablename string, scopes []store.Scope) *gorm.DB {
 	db := c.db.WithContext(ctx)
 	for _, cond := range scopes {
@@ -0,0 +1,199 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"xiaoshiai.cn/common/errors"
+	"xiaoshiai.cn/common/store"
+)
+
+// recordDriver is a database/sql driver records executed statements,
+// tables have the columns in columns, count queries return count and statements affect affected rows.
+type recordDriver struct {
+	lock     sync.Mutex
+	columns  map[string][]string
+	count    int64
+	affected int64
+	execs    []string
+}
+
+func (d *recordDriver) Open(string) (driver.Conn, error) { return &recordConn{driver: d}, nil }
+
+type recordConn struct{ driver *recordDriver }
+
+func (c *recordConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *recordConn) Close() error                        { return nil }
+func (c *recordConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *recordConn) Commit() error                       { return nil }
+func (c *recordConn) Rollback() error                     { return nil }
+
+func (c *recordConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *recordConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.driver.lock.Lock()
+	defer c.driver.lock.Unlock()
+	c.driver.execs = append(c.driver.execs, query)
+	return driver.RowsAffected(c.driver.affected), nil
+}
+
+func (c *recordConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "INFORMATION_SCHEMA.columns") || len(args) < 2 {
+		if strings.Contains(query, "count(") {
+			return &countRows{count: &c.driver.count}, nil
+		}
+		return &countRows{}, nil
+	}
+	table, column := args[len(args)-2].Value, args[len(args)-1].Value
+	count := int64(0)
+	for _, exists := range c.driver.columns[table.(string)] {
+		if exists == column {
+			count = 1
+		}
+	}
+	return &countRows{count: &count}, nil
+}
+
+type countRows struct{ count *int64 }
+
+func (r *countRows) Columns() []string { return []string{"count"} }
+func (r *countRows) Close() error      { return nil }
+func (r *countRows) Next(dest []driver.Value) error {
+	if r.count == nil {
+		return io.EOF
+	}
+	dest[0], r.count = *r.count, nil
+	return nil
+}
+
+func setupRecordStorage(t *testing.T, columns map[string][]string) (*Storage, *recordDriver) {
+	d := &recordDriver{columns: columns}
+	db, err := gorm.Open(gormpostgres.New(gormpostgres.Config{Conn: sql.OpenDB(connector{d})}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	return &Storage{core: &core{db: db, helper: NewStructHelper(), driver: DBDriverPostgres}}, d
+}
+
+type connector struct{ driver *recordDriver }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c connector) Driver() driver.Driver                        { return c.driver }
+
+type testUser struct {
+	store.ObjectMeta `json:",inline"`
+}
+
+func TestIdentityColumns(t *testing.T) {
+	ctx := store.WithIdentity(context.Background(), "alice")
+	tests := []struct {
+		name    string
+		columns []string
+		want    bool
+	}{
+		{name: "with identity columns", columns: []string{"id", "name", "createdBy", "updatedBy"}, want: true},
+		{name: "without identity columns", columns: []string{"id", "name"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, d := setupRecordStorage(t, map[string][]string{"testusers": tt.columns})
+			user := &testUser{ObjectMeta: store.ObjectMeta{ID: "u1", Name: "u1"}}
+			if err := s.Create(ctx, user); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if err := s.Update(ctx, user); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if err := s.Patch(ctx, user, store.RawPatch(store.PatchTypeMergePatch, []byte(`{"name":"u2"}`))); err != nil {
+				t.Fatalf("Patch() error = %v", err)
+			}
+			if len(d.execs) != 3 {
+				t.Fatalf("executed %v, want 3 statements", d.execs)
+			}
+			for _, exec := range d.execs {
+				if got := strings.Contains(exec, `"updatedBy"`); got != tt.want {
+					t.Errorf("statement %q writes updatedBy = %v, want %v", exec, got, tt.want)
+				}
+			}
+			if got := strings.Contains(d.execs[0], `"createdBy"`); got != tt.want {
+				t.Errorf("create %q writes createdBy = %v, want %v", d.execs[0], got, tt.want)
+			}
+			if strings.Contains(d.execs[1], `"createdBy"`) {
+				t.Errorf("update %q writes createdBy", d.execs[1])
+			}
+		})
+	}
+}
+
+func TestIdentityIgnoresClientValues(t *testing.T) {
+	s, d := setupRecordStorage(t, map[string][]string{"testusers": {"id", "name", "createdBy", "updatedBy"}})
+	user := &testUser{ObjectMeta: store.ObjectMeta{ID: "u1", Name: "u1", CreatedBy: "mallory", UpdatedBy: "mallory"}}
+	if err := s.Update(context.Background(), user); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if strings.Contains(d.execs[0], "updatedBy") || strings.Contains(d.execs[0], "createdBy") {
+		t.Errorf("update %q without identity writes identity columns", d.execs[0])
+	}
+	patch := store.RawPatch(store.PatchTypeMergePatch, []byte(`{"name":"u2","createdBy":"mallory","updatedBy":"mallory"}`))
+	if err := s.Patch(context.Background(), user, patch); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if strings.Contains(d.execs[1], "updatedBy") || strings.Contains(d.execs[1], "createdBy") {
+		t.Errorf("patch %q without identity writes identity columns", d.execs[1])
+	}
+}
+
+func TestDeleteBatch(t *testing.T) {
+	selector := store.Requirements{store.RequirementEqual("name", "u1")}
+	tests := []struct {
+		name      string
+		opts      []store.DeleteBatchOption
+		count     int64
+		wantErr   bool
+		wantExecs int
+	}{
+		{name: "unfiltered", wantErr: true},
+		{name: "allow unfiltered", opts: []store.DeleteBatchOption{store.WithDeleteBatchAllowUnfiltered()}, wantExecs: 1},
+		{name: "filtered", opts: []store.DeleteBatchOption{store.WithDeleteBatchFieldRequirements(selector...)}, wantExecs: 1},
+		{
+			name:      "within max affected",
+			opts:      []store.DeleteBatchOption{store.WithDeleteBatchFieldRequirements(selector...), store.WithDeleteBatchMaxAffected(3)},
+			count:     3,
+			wantExecs: 1,
+		},
+		{
+			name:    "exceeds max affected",
+			opts:    []store.DeleteBatchOption{store.WithDeleteBatchFieldRequirements(selector...), store.WithDeleteBatchMaxAffected(3)},
+			count:   4,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, d := setupRecordStorage(t, nil)
+			d.count, d.affected = tt.count, 3
+			list := &store.List[testUser]{}
+			err := s.DeleteBatch(context.Background(), list, tt.opts...)
+			if tt.wantErr {
+				if !errors.IsCode(err, http.StatusBadRequest) {
+					t.Errorf("DeleteBatch() error = %v, want bad request", err)
+				}
+			} else if err != nil {
+				t.Fatalf("DeleteBatch() error = %v", err)
+			} else if list.Total != 3 {
+				t.Errorf("DeleteBatch() total = %d, want the affected rows 3", list.Total)
+			}
+			if len(d.execs) != tt.wantExecs {
+				t.Errorf("executed %v, want %d statements", d.execs, tt.wantExecs)
+			}
+		})
+	}
+}
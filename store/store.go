@@ -40,6 +40,7 @@ type (
 		// ascending creation timestamp.
 		// name is alias for metadata.name
 		// time is alias for metadata.creationTimestamp
+		// createdBy and updatedBy sort by the identity created and last updated the object.
 		Sort string
 		// ResourceVersion set to 0 to get from cache
 		// ResourceVersion set to a specific value to get the object not older than that version
@@ -47,18 +48,26 @@ type (
 		ResourceVersion   *int64
 		LabelRequirements Requirements
 		FieldRequirements Requirements
-		//  IncludeSubScopes is a flag to include resources in subscopes of current scope.
+		// IncludeSubScopes is a flag to include resources in subscopes of current scope.
+		// Leaving it unset excludes them on the etcd, etcdcache and cache stores. The mongo store
+		// matches objects in sub scopes regardless, unless the resource is registered with
+		// ExcludeSubScopes, see the mongo ObjectDefination.
 		IncludeSubScopes bool
 		Continue         string
 		// Fields is a list of fields to return.  If empty, all fields are returned.
 		Fields []string
+		// SkipSizePolicy lists without the default and maximum page size of the [ListPolicy] of the resource,
+		// for internal lists which must see all objects, e.g. authorization. Do not set it from requests.
+		// It is not sent by the rest client, lists through a rest server are always bounded.
+		SkipSizePolicy bool
 	}
 	ListOption func(*ListOptions)
 
 	CountOptions struct {
 		LabelRequirements Requirements
 		FieldRequirements Requirements
-		IncludeSubScopes  bool
+		// IncludeSubScopes is as [ListOptions.IncludeSubScopes].
+		IncludeSubScopes bool
 	}
 	CountOption func(*CountOptions)
 
@@ -85,6 +94,12 @@ type (
 		LabelRequirements Requirements
 		FieldRequirements Requirements
 		DryRun            bool
+		// AllowUnfiltered allows deletion without any requirement, which deletes all objects in the scope.
+		AllowUnfiltered bool
+		// MaxAffected is the maximum number of objects allowed to be deleted, 0 means no limit.
+		// the number of matched objects is counted before deletion, it is best-effort:
+		// counting and deletion are not atomic, objects matched in between are affected beyond the limit.
+		MaxAffected int
 	}
 	DeleteBatchOption func(*DeleteBatchOptions)
 
@@ -111,6 +126,12 @@ type (
 		FieldRequirements Requirements
 		LabelRequirements Requirements
 		DryRun            bool
+		// AllowUnfiltered allows patch without any requirement, which patches all objects in the scope.
+		AllowUnfiltered bool
+		// MaxAffected is the maximum number of objects allowed to be patched, 0 means no limit.
+		// the number of matched objects is counted before patching, it is best-effort:
+		// counting and patching are not atomic, objects matched in between are affected beyond the limit.
+		MaxAffected int
 	}
 	PatchBatchOption func(*PatchBatchOptions)
 
@@ -119,6 +140,7 @@ type (
 		LabelRequirements Requirements
 		FieldRequirements Requirements
 		ResourceVersion   *int64
+		// IncludeSubScopes is as [ListOptions.IncludeSubScopes].
 		IncludeSubScopes  bool
 		SendInitialEvents bool
 		DryRun            bool
@@ -300,6 +322,32 @@ func WithDeleteBatchLabelRequirements(reqs ...Requirement) DeleteBatchOption {
 	}
 }
 
+// WithPatchBatchAllowUnfiltered allows PatchBatch without requirements, it patches all objects in the scope.
+func WithPatchBatchAllowUnfiltered() PatchBatchOption {
+	return func(o *PatchBatchOptions) {
+		o.AllowUnfiltered = true
+	}
+}
+
+func WithPatchBatchMaxAffected(max int) PatchBatchOption {
+	return func(o *PatchBatchOptions) {
+		o.MaxAffected = max
+	}
+}
+
+// WithDeleteBatchAllowUnfiltered allows DeleteBatch without requirements, it deletes all objects in the scope.
+func WithDeleteBatchAllowUnfiltered() DeleteBatchOption {
+	return func(o *DeleteBatchOptions) {
+		o.AllowUnfiltered = true
+	}
+}
+
+func WithDeleteBatchMaxAffected(max int) DeleteBatchOption {
+	return func(o *DeleteBatchOptions) {
+		o.MaxAffected = max
+	}
+}
+
 func WithDeleteFieldRequirements(reqs ...Requirement) DeleteOption {
 	return func(o *DeleteOptions) {
 		o.FieldRequirements = append(o.FieldRequirements, reqs...)
@@ -331,6 +379,13 @@ func WithPageSize(page, size int) ListOption {
 	}
 }
 
+// WithoutSizePolicy lists without the page size policy of the resource, see [ListOptions.SkipSizePolicy].
+func WithoutSizePolicy() ListOption {
+	return func(o *ListOptions) {
+		o.SkipSizePolicy = true
+	}
+}
+
 func WithSort(sort string) ListOption {
 	return func(o *ListOptions) {
 		o.Sort = sort
@@ -431,11 +486,16 @@ type Store interface {
 	Count(ctx context.Context, obj Object, opts ...CountOption) (int, error)
 	Create(ctx context.Context, obj Object, opts ...CreateOption) error
 	Delete(ctx context.Context, obj Object, opts ...DeleteOption) error
+	// DeleteBatch deletes all objects matching the requirements.
+	// it requires at least one requirement unless AllowUnfiltered is set,
+	// the number of deleted objects is set to the total of the list.
 	DeleteBatch(ctx context.Context, obj ObjectList, opts ...DeleteBatchOption) error
 	Update(ctx context.Context, obj Object, opts ...UpdateOption) error
 	Patch(ctx context.Context, obj Object, patch Patch, opts ...PatchOption) error
 	// PatchBatch applies the patch to all objects in the list.
 	// the patch is applied to each object in the list.
+	// it requires at least one requirement unless AllowUnfiltered is set,
+	// the number of matched objects is set to the total of the list.
 	PatchBatch(ctx context.Context, obj ObjectList, patch PatchBatch, opts ...PatchBatchOption) error
 	Watch(ctx context.Context, obj ObjectList, opts ...WatchOption) (Watcher, error)
 	Status() StatusStorage
@@ -261,6 +261,34 @@ func (u *Unstructured) SetOwnerReferences(references []OwnerReference) {
 	u.setNestedField(newReferences, "ownerReferences")
 }
 
+// GetCreatedBy implements Object.
+func (u *Unstructured) GetCreatedBy() string {
+	return GetNestedString(u.Object, "createdBy")
+}
+
+// SetCreatedBy implements Object.
+func (u *Unstructured) SetCreatedBy(createdBy string) {
+	if createdBy == "" {
+		RemoveNestedField(u.Object, "createdBy")
+		return
+	}
+	u.setNestedField(createdBy, "createdBy")
+}
+
+// GetUpdatedBy implements Object.
+func (u *Unstructured) GetUpdatedBy() string {
+	return GetNestedString(u.Object, "updatedBy")
+}
+
+// SetUpdatedBy implements Object.
+func (u *Unstructured) SetUpdatedBy(updatedBy string) {
+	if updatedBy == "" {
+		RemoveNestedField(u.Object, "updatedBy")
+		return
+	}
+	u.setNestedField(updatedBy, "updatedBy")
+}
+
 func (u *Unstructured) setNestedField(value any, fields ...string) {
 	if u.Object == nil {
 		u.Object = make(map[string]any)
@@ -500,7 +528,7 @@ func CompareDataFieldSort(a, b *Unstructured, sort meta.SortField) int {
 		default:
 			return 0
 		}
-	case "time":
+	case "time", "creationTimestamp":
 		at, bt := a.GetCreationTimestamp(), b.GetCreationTimestamp()
 		switch sort.Direction {
 		case meta.SortDirectionAsc: